@@ -0,0 +1,282 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// indices declara los índices que necesita cada colección del post-service.
+var indices = map[string][]mongo.IndexModel{
+	"follows": {
+		{Keys: bson.D{{Key: "seguidorId", Value: 1}, {Key: "seguidoId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "seguidoId", Value: 1}, {Key: "fecha", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "seguidorId", Value: 1}, {Key: "fecha", Value: -1}, {Key: "_id", Value: -1}}},
+	},
+	"bloqueos": {
+		{Keys: bson.D{{Key: "bloqueadorId", Value: 1}, {Key: "bloqueadoId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "bloqueadoId", Value: 1}}},
+	},
+	"silencios": {
+		{Keys: bson.D{{Key: "usuarioId", Value: 1}, {Key: "silenciadoId", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	"sugerencias_descartadas": {
+		{Keys: bson.D{{Key: "usuarioId", Value: 1}, {Key: "descartadoId", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	"comentarios": {
+		{
+			Keys:    bson.D{{Key: "contenido", Value: "text"}},
+			Options: options.Index().SetDefaultLanguage("spanish").SetLanguageOverride("idioma"),
+		},
+		{Keys: bson.D{{Key: "postId", Value: 1}, {Key: "parentId", Value: 1}, {Key: "fecha", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "postId", Value: 1}, {Key: "parentId", Value: 1}, {Key: "totalLikes", Value: -1}, {Key: "_id", Value: -1}}},
+	},
+	"reacciones": {
+		{Keys: bson.D{{Key: "postId", Value: 1}, {Key: "comentarioId", Value: 1}, {Key: "usuarioId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "postId", Value: 1}, {Key: "comentarioId", Value: 1}, {Key: "fecha", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "postId", Value: 1}, {Key: "comentarioId", Value: 1}, {Key: "tipo", Value: 1}, {Key: "fecha", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "usuarioId", Value: 1}, {Key: "fecha", Value: -1}}},
+	},
+	"adjuntos": {
+		{Keys: bson.D{{Key: "archivo", Value: 1}}},
+		{Keys: bson.D{{Key: "hash", Value: 1}, {Key: "procesamiento", Value: 1}}},
+		{Keys: bson.D{{Key: "subidoPor", Value: 1}, {Key: "fecha", Value: -1}}},
+	},
+	"trabajos_media": {
+		{Keys: bson.D{{Key: "estado", Value: 1}, {Key: "fecha", Value: 1}}},
+	},
+	"posts_papelera": {
+		{Keys: bson.D{{Key: "eliminadoEn", Value: 1}}},
+		{Keys: bson.D{{Key: "autorId", Value: 1}}},
+		{Keys: bson.D{{Key: "adjuntos._id", Value: 1}}},
+		{Keys: bson.D{{Key: "repostDe", Value: 1}}},
+	},
+	"purgas_posts": {
+		{Keys: bson.D{{Key: "fecha", Value: 1}}},
+	},
+	"notificaciones": {
+		{Keys: bson.D{{Key: "postId", Value: 1}}},
+	},
+	"guardados": {
+		{Keys: bson.D{{Key: "usuarioId", Value: 1}, {Key: "postId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "usuarioId", Value: 1}, {Key: "fecha", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "usuarioId", Value: 1}, {Key: "colecciones", Value: 1}, {Key: "fecha", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "postId", Value: 1}}},
+	},
+	"grupos": {
+		{Keys: bson.D{{Key: "duenoId", Value: 1}, {Key: "nombre", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "miembros", Value: 1}}},
+	},
+	"colecciones_guardados": {
+		{Keys: bson.D{{Key: "usuarioId", Value: 1}, {Key: "nombre", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	"tag_usos": {
+		{Keys: bson.D{{Key: "fecha", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(60 * 60 * 24 * 40)},
+		{Keys: bson.D{{Key: "tag", Value: 1}, {Key: "fecha", Value: -1}}},
+		{Keys: bson.D{{Key: "postId", Value: 1}}},
+	},
+	"post_revisiones": {
+		{Keys: bson.D{{Key: "postId", Value: 1}, {Key: "fecha", Value: -1}, {Key: "_id", Value: -1}}},
+	},
+	"timelines": {
+		{Keys: bson.D{{Key: "usuarioId", Value: 1}, {Key: "postId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "usuarioId", Value: 1}, {Key: "fechaCreado", Value: -1}, {Key: "postId", Value: -1}}},
+		{Keys: bson.D{{Key: "usuarioId", Value: 1}, {Key: "autorId", Value: 1}}},
+		{Keys: bson.D{{Key: "postId", Value: 1}}},
+	},
+	"rankings_para_ti": {
+		// Una hora alcanza para recorrer el feed; después se vuelve a rankear
+		{Keys: bson.D{{Key: "fecha", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(60 * 60)},
+	},
+	"follow_requests": {
+		{Keys: bson.D{{Key: "seguidorId", Value: 1}, {Key: "seguidoId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "seguidoId", Value: 1}, {Key: "fecha", Value: -1}, {Key: "_id", Value: -1}}},
+	},
+}
+
+// prefijosPosts son los campos por igualdad con los que empiezan los índices de
+// los listados de posts. Todos filtran por estado (ver utils.FiltroPublicados); el
+// resto de las condiciones (visibilidad, tipo, categoría) se evalúan sobre el
+// recorrido del índice.
+var prefijosPosts = [][]string{
+	{"estado"},            // GET /posts, búsqueda por filtros
+	{"estado", "tags"},    // ?tag= y páginas de tags
+	{"autorId", "estado"}, // perfil, borradores y feed al vuelo
+}
+
+// ordenesPosts son los campos por los que se pueden ordenar los listados de posts.
+var ordenesPosts = []string{"fechaCreado", "totalLikes", "totalComentarios"}
+
+// filtrosPostsAnteriores son los prefijos de los índices de listados que se
+// creaban antes de filtrar por visibilidad y estado. Ninguna consulta los usa y
+// cada uno encarece las escrituras, así que se borran al arrancar.
+var filtrosPostsAnteriores = [][]string{
+	{}, {"tipo"}, {"categoria"}, {"tags"},
+	{"tipo", "categoria"}, {"tipo", "tags"}, {"categoria", "tags"},
+	{"tipo", "categoria", "tags"},
+	{"autorId"},
+}
+
+// indicesObsoletos indica, por colección, los nombres de índices que se borran
+// al arrancar.
+var indicesObsoletos = map[string][]string{}
+
+func init() {
+	// Búsqueda de texto completo (GET /search). La versión 3 del índice ya ignora
+	// tildes y mayúsculas; "idioma" elige el stemmer de cada documento. Incluye el
+	// texto extraído de los documentos adjuntos.
+	indices["posts"] = append(indices["posts"], mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "titulo", Value: "text"}, {Key: "contenido", Value: "text"},
+			{Key: "tags", Value: "text"}, {Key: "adjuntos.texto", Value: "text"},
+		},
+		Options: options.Index().
+			SetName("busqueda_texto").
+			SetWeights(bson.M{"titulo": 5, "tags": 3, "contenido": 1, "adjuntos.texto": 1}).
+			SetDefaultLanguage("spanish").
+			SetLanguageOverride("idioma"),
+	})
+
+	// Para saber si un adjunto sigue en uso al purgar un post
+	indices["posts"] = append(indices["posts"], mongo.IndexModel{Keys: bson.D{{Key: "adjuntos._id", Value: 1}}})
+
+	// Un usuario comparte cada post una sola vez; repostDe y citaDe sirven además
+	// para contar reposts y citas y para purgarlos con el original
+	indices["posts"] = append(indices["posts"],
+		mongo.IndexModel{
+			Keys: bson.D{{Key: "autorId", Value: 1}, {Key: "repostDe", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"repostDe": bson.M{"$exists": true}}),
+		},
+		mongo.IndexModel{Keys: bson.D{{Key: "repostDe", Value: 1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "citaDe", Value: 1}}},
+	)
+
+	// El publicador busca los posts programados que vencieron y las difusiones que
+	// quedaron a medias; ambos índices contienen solo esos pocos posts
+	indices["posts"] = append(indices["posts"],
+		mongo.IndexModel{
+			Keys:    bson.D{{Key: "publicarEn", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"estado": "scheduled"}),
+		},
+		mongo.IndexModel{
+			Keys:    bson.D{{Key: "difundiendo", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"difundiendo": bson.M{"$exists": true}}),
+		},
+	)
+
+	// Un índice compuesto por cada prefijo + orden, con _id como desempate del
+	// cursor (keyset) para que cada página sea un recorrido de índice.
+	for _, prefijo := range prefijosPosts {
+		for _, orden := range ordenesPosts {
+			indices["posts"] = append(indices["posts"], mongo.IndexModel{Keys: clavesListado(prefijo, orden)})
+		}
+	}
+	for _, prefijo := range filtrosPostsAnteriores {
+		for _, orden := range ordenesPosts {
+			indicesObsoletos["posts"] = append(indicesObsoletos["posts"], nombreIndice(clavesListado(prefijo, orden)))
+		}
+	}
+}
+
+// clavesListado arma las claves {prefijo...: 1, orden: -1, _id: -1}.
+func clavesListado(prefijo []string, orden string) bson.D {
+	keys := bson.D{}
+	for _, campo := range prefijo {
+		keys = append(keys, bson.E{Key: campo, Value: 1})
+	}
+	return append(keys, bson.E{Key: orden, Value: -1}, bson.E{Key: "_id", Value: -1})
+}
+
+// nombreIndice devuelve el nombre que Mongo le da por defecto a un índice con
+// esas claves ("campo_1_otro_-1").
+func nombreIndice(keys bson.D) string {
+	partes := make([]string, 0, len(keys))
+	for _, k := range keys {
+		partes = append(partes, fmt.Sprintf("%s_%v", k.Key, k.Value))
+	}
+	return strings.Join(partes, "_")
+}
+
+// Códigos de error de Mongo cuando ya existe un índice con el mismo nombre pero
+// otra definición.
+const (
+	codigoIndexOptionsConflict  = 85
+	codigoIndexKeySpecsConflict = 86
+	codigoIndexNotFound         = 27
+	codigoNamespaceNotFound     = 26
+)
+
+// CrearIndices borra los índices de indicesObsoletos y crea (si no existen) los
+// declarados en indices.
+// Un fallo no detiene el servicio: solo se registra en el log.
+func CrearIndices() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for coleccion, nombres := range indicesObsoletos {
+		vista := DB.Collection(coleccion).Indexes()
+		for _, nombre := range nombres {
+			_, err := vista.DropOne(ctx, nombre)
+			if err == nil {
+				log.Println("Índice obsoleto borrado:", coleccion, nombre)
+			} else if !esCodigoDeError(err, codigoIndexNotFound, codigoNamespaceNotFound) {
+				log.Println("Error borrando el índice", nombre, "de", coleccion, ":", err)
+			}
+		}
+	}
+
+	for coleccion, modelos := range indices {
+		vista := DB.Collection(coleccion).Indexes()
+		_, err := vista.CreateMany(ctx, modelos)
+		if err == nil {
+			continue
+		}
+		if !esConflictoDeIndice(err) {
+			log.Println("Error creando índices de", coleccion, ":", err)
+			continue
+		}
+		// Algún índice con nombre propio cambió de definición: se crean de a uno y
+		// el que choca se reemplaza
+		for _, modelo := range modelos {
+			_, err := vista.CreateOne(ctx, modelo)
+			if esConflictoDeIndice(err) && modelo.Options != nil && modelo.Options.Name != nil {
+				log.Println("Reemplazando el índice", *modelo.Options.Name, "de", coleccion)
+				if _, err = vista.DropOne(ctx, *modelo.Options.Name); err == nil {
+					_, err = vista.CreateOne(ctx, modelo)
+				}
+			}
+			if err != nil {
+				log.Println("Error creando índices de", coleccion, ":", err)
+			}
+		}
+	}
+}
+
+// esConflictoDeIndice indica si err se debe a un índice existente con el mismo
+// nombre y otra definición.
+func esConflictoDeIndice(err error) bool {
+	return esCodigoDeError(err, codigoIndexOptionsConflict, codigoIndexKeySpecsConflict)
+}
+
+// esCodigoDeError indica si err es un error de comando de Mongo con alguno de
+// los códigos indicados.
+func esCodigoDeError(err error, codigos ...int32) bool {
+	var cmdErr mongo.CommandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+	for _, codigo := range codigos {
+		if cmdErr.Code == codigo {
+			return true
+		}
+	}
+	return false
+}
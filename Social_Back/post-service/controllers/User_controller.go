@@ -12,6 +12,7 @@ import (
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // SeguirUsuario crea una relación seguidor → seguido y notifica al seguido si aplica.
@@ -42,6 +43,15 @@ func SeguirUsuario(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, echo.Map{"message": "No puedes seguirte a ti mismo"})
 	}
 
+	// No se permite seguir si existe un bloqueo en cualquier sentido
+	bloqueado, err := utils.HayBloqueo(seguidorID, seguidoID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar bloqueos"})
+	}
+	if bloqueado {
+		return c.JSON(http.StatusForbidden, echo.Map{"message": "No puedes seguir a este usuario"})
+	}
+
 	// Verificar si ya existe la relación
 	collection := config.GetCollection("follows")
 	filter := bson.M{"seguidorId": seguidorID, "seguidoId": seguidoID}
@@ -52,6 +62,15 @@ func SeguirUsuario(c echo.Context) error {
 		return c.JSON(http.StatusConflict, echo.Map{"message": "Ya sigues a este usuario"})
 	}
 
+	// Las cuentas privadas reciben una solicitud en lugar de un follow directo
+	ajustes, err := utils.ObtenerAjustes(seguidoID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar la privacidad del usuario"})
+	}
+	if ajustes.CuentaPrivada {
+		return crearSolicitudSeguimiento(c, seguidorID, seguidoID)
+	}
+
 	// Registrar follow
 	newFollow := models.Follow{
 		ID:         primitive.NewObjectID(),
@@ -60,11 +79,15 @@ func SeguirUsuario(c echo.Context) error {
 		Fecha:      time.Now(),
 	}
 	_, err = collection.InsertOne(context.TODO(), newFollow)
+	if mongo.IsDuplicateKeyError(err) {
+		return c.JSON(http.StatusConflict, echo.Map{"message": "Ya sigues a este usuario"})
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al guardar follow"})
 	}
 
 	log.Println("Follow registrado:", seguidorID.Hex(), "->", seguidoID.Hex())
+	go utils.AgregarAutorATimeline(seguidorID, seguidoID)
 	utils.CrearNotificacion("follow", seguidorID, seguidoID, "Empezó a seguirte", nil)
 
 	return c.JSON(http.StatusCreated, echo.Map{"message": "Ahora sigues al usuario"})
@@ -94,42 +117,133 @@ func DejarDeSeguir(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al dejar de seguir"})
 	}
+	utils.QuitarAutorDeTimeline(seguidorID, seguidoID)
 
 	return c.JSON(http.StatusOK, echo.Map{"message": "Dejaste de seguir al usuario"})
 }
 
-// VerSeguidores retorna quienes siguen a un usuario
+// VerSeguidores retorna quienes siguen a un usuario, paginado y con su perfil público
 func VerSeguidores(c echo.Context) error {
-	id, _ := primitive.ObjectIDFromHex(c.Param("id"))
+	return listarFollows(c, "seguidoId", "seguidorId", "Error al buscar seguidores")
+}
 
-	collection := config.GetCollection("follows")
-	cursor, err := collection.Find(context.TODO(), bson.M{"seguidoId": id}) // 👈 CORRECTO
+// VerSeguidos retorna a quién sigue un usuario, paginado y con su perfil público
+func VerSeguidos(c echo.Context) error {
+	return listarFollows(c, "seguidorId", "seguidoId", "Error al buscar seguidos")
+}
+
+// listarFollows pagina la colección follows filtrando por campoPropio = :id y une el
+// perfil del usuario referenciado en campoOtro. total es la cantidad de follows de
+// :id, no la de la página. Si se envía ?viewerId= se calculan las banderas
+// loSigo/meSigue respecto a ese usuario.
+func listarFollows(c echo.Context, campoPropio, campoOtro, mensajeError string) error {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar seguidores"})
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de usuario inválido"})
 	}
-	defer cursor.Close(context.TODO())
 
-	var seguidores []models.Follow
-	cursor.All(context.TODO(), &seguidores)
+	viewerID, err := viewerDesdeQuery(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "viewerId inválido"})
+	}
 
-	return c.JSON(http.StatusOK, seguidores)
-}
+	cursor, err := utils.DecodificarCursor(c.QueryParam("cursor"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Cursor inválido"})
+	}
+	limite := utils.ParsearLimite(c.QueryParam("limit"), 20, 100)
 
-// VerSeguidos retorna a quién sigue un usuario
-func VerSeguidos(c echo.Context) error {
-	id, _ := primitive.ObjectIDFromHex(c.Param("id"))
+	match := bson.M{campoPropio: id}
+	if cursor != nil {
+		match = bson.M{"$and": []bson.M{match, utils.FiltroDespuesDe("fecha", cursor)}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "fecha", Value: -1}, {Key: "_id", Value: -1}}}},
+		// Se pide uno extra para saber si existe una página siguiente
+		{{Key: "$limit", Value: limite + 1}},
+	}
+	pipeline = append(pipeline, etapasUsuarioPublico(campoOtro, "usuario")...)
+
+	if !viewerID.IsZero() {
+		pipeline = append(pipeline,
+			bson.D{{Key: "$lookup", Value: bson.M{
+				"from": "follows",
+				"let":  bson.M{"otro": "$" + campoOtro},
+				"pipeline": bson.A{
+					bson.M{"$match": bson.M{"$expr": bson.M{"$and": bson.A{
+						bson.M{"$eq": bson.A{"$seguidorId", viewerID}},
+						bson.M{"$eq": bson.A{"$seguidoId", "$$otro"}},
+					}}}},
+					bson.M{"$limit": 1},
+				},
+				"as": "_loSigo",
+			}}},
+			bson.D{{Key: "$lookup", Value: bson.M{
+				"from": "follows",
+				"let":  bson.M{"otro": "$" + campoOtro},
+				"pipeline": bson.A{
+					bson.M{"$match": bson.M{"$expr": bson.M{"$and": bson.A{
+						bson.M{"$eq": bson.A{"$seguidorId", "$$otro"}},
+						bson.M{"$eq": bson.A{"$seguidoId", viewerID}},
+					}}}},
+					bson.M{"$limit": 1},
+				},
+				"as": "_meSigue",
+			}}},
+			bson.D{{Key: "$addFields", Value: bson.M{
+				"loSigo":  bson.M{"$gt": bson.A{bson.M{"$size": "$_loSigo"}, 0}},
+				"meSigue": bson.M{"$gt": bson.A{bson.M{"$size": "$_meSigue"}, 0}},
+			}}},
+			bson.D{{Key: "$project", Value: bson.M{"_loSigo": 0, "_meSigue": 0}}},
+		)
+	}
 
 	collection := config.GetCollection("follows")
-	cursor, err := collection.Find(context.TODO(), bson.M{"seguidorId": id}) // 👈 CORRECTO
+	cur, err := collection.Aggregate(context.TODO(), pipeline)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar seguidos"})
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": mensajeError})
 	}
-	defer cursor.Close(context.TODO())
+	defer cur.Close(context.TODO())
 
-	var seguidos []models.Follow
-	cursor.All(context.TODO(), &seguidos)
+	follows := []models.FollowDetalle{}
+	if err := cur.All(context.TODO(), &follows); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": mensajeError})
+	}
+
+	var siguiente string
+	if len(follows) > limite {
+		follows = follows[:limite]
+		ultimo := follows[limite-1]
+		siguiente = utils.CodificarCursor(ultimo.Fecha, ultimo.ID)
+	}
 
-	return c.JSON(http.StatusOK, seguidos)
+	// El total de seguidores/seguidos, no el de esta página
+	total, err := collection.CountDocuments(context.TODO(), bson.M{campoPropio: id})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": mensajeError})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": follows, "nextCursor": siguiente, "total": total})
+}
+
+// proyeccionUsuarioPublico lee de "users" solo los campos de models.UsuarioPublico.
+var proyeccionUsuarioPublico = bson.M{"username": 1, "rol": 1, "profilePicture": 1}
+
+// etapasUsuarioPublico une el perfil público (models.UsuarioPublico) del usuario
+// referenciado en campoLocal y lo deja en el campo destino (nulo si no existe).
+func etapasUsuarioPublico(campoLocal, destino string) []bson.D {
+	return []bson.D{
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   campoLocal,
+			"foreignField": "_id",
+			"as":           destino,
+			"pipeline":     bson.A{bson.M{"$project": proyeccionUsuarioPublico}},
+		}}},
+		{{Key: "$unwind", Value: bson.M{"path": "$" + destino, "preserveNullAndEmptyArrays": true}}},
+	}
 }
 
 // Ver notificaciones al usuario
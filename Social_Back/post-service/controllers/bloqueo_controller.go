@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"post-service/config"
+	"post-service/models"
+	"post-service/utils"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// BloquearUsuario registra que usuarioId (cuerpo) bloquea a :id. Además elimina los
+// follows y solicitudes pendientes que existan entre ambos en cualquier sentido.
+func BloquearUsuario(c echo.Context) error {
+	bloqueadoID, bloqueadorID, ok := parsearRestriccion(c)
+	if !ok {
+		return nil
+	}
+
+	bloqueo := models.Bloqueo{
+		ID:           primitive.NewObjectID(),
+		BloqueadorID: bloqueadorID,
+		BloqueadoID:  bloqueadoID,
+		Fecha:        time.Now(),
+	}
+	_, err := config.GetCollection("bloqueos").InsertOne(context.TODO(), bloqueo)
+	if mongo.IsDuplicateKeyError(err) {
+		return c.JSON(http.StatusConflict, echo.Map{"message": "Ya bloqueaste a este usuario"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al bloquear"})
+	}
+
+	entreAmbos := bson.M{"$or": []bson.M{
+		{"seguidorId": bloqueadorID, "seguidoId": bloqueadoID},
+		{"seguidorId": bloqueadoID, "seguidoId": bloqueadorID},
+	}}
+	for _, coleccion := range []string{"follows", "follow_requests"} {
+		if _, err := config.GetCollection(coleccion).DeleteMany(context.TODO(), entreAmbos); err != nil {
+			log.Println("Error limpiando", coleccion, "tras bloqueo:", err)
+		}
+	}
+
+	utils.QuitarAutorDeTimeline(bloqueadorID, bloqueadoID)
+	utils.QuitarAutorDeTimeline(bloqueadoID, bloqueadorID)
+
+	log.Println("Bloqueo registrado:", bloqueadorID.Hex(), "->", bloqueadoID.Hex())
+	return c.JSON(http.StatusCreated, echo.Map{"message": "Usuario bloqueado"})
+}
+
+// DesbloquearUsuario elimina el bloqueo de usuarioId (cuerpo) sobre :id.
+// Los follows eliminados al bloquear no se restauran.
+func DesbloquearUsuario(c echo.Context) error {
+	bloqueadoID, bloqueadorID, ok := parsearRestriccion(c)
+	if !ok {
+		return nil
+	}
+
+	result, err := config.GetCollection("bloqueos").DeleteOne(context.TODO(), bson.M{
+		"bloqueadorId": bloqueadorID,
+		"bloqueadoId":  bloqueadoID,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al desbloquear"})
+	}
+	if result.DeletedCount == 0 {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "No habías bloqueado a este usuario"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"message": "Usuario desbloqueado"})
+}
+
+// SilenciarUsuario oculta el contenido de :id en los listados de usuarioId (cuerpo).
+func SilenciarUsuario(c echo.Context) error {
+	silenciadoID, usuarioID, ok := parsearRestriccion(c)
+	if !ok {
+		return nil
+	}
+
+	silencio := models.Silencio{
+		ID:           primitive.NewObjectID(),
+		UsuarioID:    usuarioID,
+		SilenciadoID: silenciadoID,
+		Fecha:        time.Now(),
+	}
+	_, err := config.GetCollection("silencios").InsertOne(context.TODO(), silencio)
+	if mongo.IsDuplicateKeyError(err) {
+		return c.JSON(http.StatusConflict, echo.Map{"message": "Ya silenciaste a este usuario"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al silenciar"})
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{"message": "Usuario silenciado"})
+}
+
+// DejarDeSilenciar vuelve a mostrar el contenido de :id a usuarioId (cuerpo).
+func DejarDeSilenciar(c echo.Context) error {
+	silenciadoID, usuarioID, ok := parsearRestriccion(c)
+	if !ok {
+		return nil
+	}
+
+	result, err := config.GetCollection("silencios").DeleteOne(context.TODO(), bson.M{
+		"usuarioId":    usuarioID,
+		"silenciadoId": silenciadoID,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al dejar de silenciar"})
+	}
+	if result.DeletedCount == 0 {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "No habías silenciado a este usuario"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"message": "Usuario ya no está silenciado"})
+}
+
+// VerBloqueados lista a quién bloqueó el usuario :id, paginado y con su perfil público.
+func VerBloqueados(c echo.Context) error {
+	return listarRestricciones(c, "bloqueos", "bloqueadorId", "bloqueadoId")
+}
+
+// VerSilenciados lista a quién silenció el usuario :id, paginado y con su perfil público.
+func VerSilenciados(c echo.Context) error {
+	return listarRestricciones(c, "silencios", "usuarioId", "silenciadoId")
+}
+
+// filaRestriccion es un elemento de los listados de bloqueados/silenciados.
+type filaRestriccion struct {
+	ID      primitive.ObjectID     `bson:"_id" json:"id"`
+	Fecha   time.Time              `bson:"fecha" json:"fecha"`
+	Usuario *models.UsuarioPublico `bson:"usuario" json:"usuario"`
+}
+
+// parsearRestriccion valida el :id de la ruta (usuario afectado) y el usuarioId del
+// cuerpo (quien aplica la restricción). Si algo falla ya respondió y devuelve ok=false.
+func parsearRestriccion(c echo.Context) (objetivo, usuario primitive.ObjectID, ok bool) {
+	objetivo, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de usuario inválido"})
+		return objetivo, usuario, false
+	}
+
+	var body struct {
+		UsuarioID string `json:"usuarioId"`
+	}
+	if err := c.Bind(&body); err != nil {
+		c.JSON(http.StatusBadRequest, echo.Map{"message": "Datos inválidos"})
+		return objetivo, usuario, false
+	}
+	usuario, err = primitive.ObjectIDFromHex(body.UsuarioID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, echo.Map{"message": "usuarioId inválido"})
+		return objetivo, usuario, false
+	}
+
+	if objetivo == usuario {
+		c.JSON(http.StatusBadRequest, echo.Map{"message": "No puedes aplicarte esto a ti mismo"})
+		return objetivo, usuario, false
+	}
+	return objetivo, usuario, true
+}
+
+// listarRestricciones pagina una colección de bloqueos/silencios del usuario :id
+// uniendo el perfil público del usuario restringido.
+func listarRestricciones(c echo.Context, coleccion, campoPropio, campoOtro string) error {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de usuario inválido"})
+	}
+
+	cursor, err := utils.DecodificarCursor(c.QueryParam("cursor"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Cursor inválido"})
+	}
+	limite := utils.ParsearLimite(c.QueryParam("limit"), 20, 100)
+
+	match := bson.M{campoPropio: id}
+	if cursor != nil {
+		match = bson.M{"$and": []bson.M{match, utils.FiltroDespuesDe("fecha", cursor)}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "fecha", Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$limit", Value: limite + 1}},
+	}
+	pipeline = append(pipeline, etapasUsuarioPublico(campoOtro, "usuario")...)
+
+	cur, err := config.GetCollection(coleccion).Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar usuarios"})
+	}
+	defer cur.Close(context.TODO())
+
+	filas := []filaRestriccion{}
+	if err := cur.All(context.TODO(), &filas); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer usuarios"})
+	}
+
+	var siguiente string
+	if len(filas) > limite {
+		filas = filas[:limite]
+		ultima := filas[limite-1]
+		siguiente = utils.CodificarCursor(ultima.Fecha, ultima.ID)
+	}
+	return c.JSON(http.StatusOK, echo.Map{"data": filas, "nextCursor": siguiente})
+}
@@ -0,0 +1,432 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"post-service/config"
+	"post-service/models"
+	"post-service/utils"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxProfundidadComentarios es el nivel máximo de anidación de las respuestas
+// (0 = comentario de primer nivel). Las respuestas más profundas se agregan al
+// hilo del comentario respondido en lugar de abrir un nivel nuevo.
+const maxProfundidadComentarios = 3
+
+// VerRespuestas devuelve paginadas (con los mismos parámetros que GET
+// /posts/:id/comments) las respuestas directas al comentario :commentId. Cada una incluye
+// totalRespuestas para que el cliente pueda seguir expandiendo el hilo.
+func VerRespuestas(c echo.Context) error {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID del post inválido"})
+	}
+	comentarioID, err := primitive.ObjectIDFromHex(c.Param("commentId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID del comentario inválido"})
+	}
+	viewerID, err := viewerDesdeQuery(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "viewerId inválido"})
+	}
+	if !postVisible(c, viewerID, postID) {
+		return nil
+	}
+
+	return paginarComentarios(c, bson.M{"postId": postID, "parentId": comentarioID})
+}
+
+// ordenesComentarios asocia cada valor aceptado en ?orden= con el orden aplicado.
+var ordenesComentarios = map[string]bson.D{
+	"antiguos":  {{Key: "fecha", Value: 1}, {Key: "_id", Value: 1}},
+	"recientes": {{Key: "fecha", Value: -1}, {Key: "_id", Value: -1}},
+	"top":       {{Key: "totalLikes", Value: -1}, {Key: "_id", Value: -1}},
+}
+
+// paginarComentarios responde con la página de comentarios que cumplen filtro según
+// ?orden= (antiguos por defecto, recientes o top), ?limit= y ?cursor=. Cada
+// comentario incluye el perfil público de su autor y, si se envía ?viewerId=, su
+// reacción. También devuelve el total de comentarios del listado.
+func paginarComentarios(c echo.Context, filtro bson.M) error {
+	orden := c.QueryParam("orden")
+	if orden == "" {
+		orden = "antiguos"
+	}
+	sortOrden, ok := ordenesComentarios[orden]
+	if !ok {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Orden no válido (antiguos, recientes, top)"})
+	}
+	// Cada cursor lleva su orden: el de antiguos no sirve para recientes ni al revés
+	cursor, err := utils.DecodificarCursor(c.QueryParam("cursor"))
+	if err != nil || (cursor != nil && cursor.Orden != orden) {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Cursor inválido"})
+	}
+	viewerID, err := viewerDesdeQuery(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "viewerId inválido"})
+	}
+	limite := utils.ParsearLimite(c.QueryParam("limit"), 20, 100)
+
+	// No mostrar comentarios de usuarios con los que el viewer tiene un bloqueo
+	bloqueados, err := utils.UsuariosBloqueados(viewerID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar bloqueos"})
+	}
+	if len(bloqueados) > 0 {
+		filtro["autorId"] = bson.M{"$nin": bloqueados}
+	}
+
+	collection := config.GetCollection("comentarios")
+	total, err := collection.CountDocuments(context.TODO(), filtro)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al contar los comentarios"})
+	}
+
+	if cursor != nil {
+		var despues bson.M
+		switch orden {
+		case "top":
+			despues = utils.FiltroDespuesDeConteo("totalLikes", cursor)
+		case "recientes":
+			despues = utils.FiltroDespuesDe("fecha", cursor)
+		default:
+			despues = utils.FiltroDespuesDeAscendente("fecha", cursor)
+		}
+		filtro = bson.M{"$and": []bson.M{filtro, despues}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filtro}},
+		{{Key: "$sort", Value: sortOrden}},
+		// Se pide uno extra para saber si existe una página siguiente
+		{{Key: "$limit", Value: limite + 1}},
+	}
+	pipeline = append(pipeline, etapasUsuarioPublico("autorId", "autor")...)
+	if !viewerID.IsZero() {
+		pipeline = append(pipeline,
+			bson.D{{Key: "$lookup", Value: bson.M{
+				"from": "reacciones",
+				"let":  bson.M{"post": "$postId", "comentario": "$_id"},
+				"pipeline": bson.A{
+					bson.M{"$match": bson.M{"$expr": bson.M{"$and": bson.A{
+						bson.M{"$eq": bson.A{"$postId", "$$post"}},
+						bson.M{"$eq": bson.A{"$comentarioId", "$$comentario"}},
+						bson.M{"$eq": bson.A{"$usuarioId", viewerID}},
+					}}}},
+					bson.M{"$limit": 1},
+				},
+				"as": "_reaccion",
+			}}},
+			bson.D{{Key: "$addFields", Value: bson.M{
+				"leGusta":  bson.M{"$gt": bson.A{bson.M{"$size": "$_reaccion"}, 0}},
+				"reaccion": bson.M{"$arrayElemAt": bson.A{"$_reaccion.tipo", 0}},
+			}}},
+			bson.D{{Key: "$project", Value: bson.M{"_reaccion": 0}}},
+		)
+	}
+
+	cur, err := collection.Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar los comentarios"})
+	}
+	defer cur.Close(context.TODO())
+
+	comentarios := []models.ComentarioDetalle{}
+	if err := cur.All(context.TODO(), &comentarios); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer los comentarios"})
+	}
+	for i := range comentarios {
+		cm := &comentarios[i]
+		// De un comentario eliminado solo queda el lugar que ocupa en el hilo
+		if cm.Eliminado {
+			cm.Autor = nil
+		}
+		if cm.Autor != nil {
+			cm.Username = cm.Autor.Username
+		}
+		cm.Text = cm.Contenido
+	}
+
+	var siguiente string
+	if len(comentarios) > limite {
+		comentarios = comentarios[:limite]
+		ultimo := comentarios[limite-1]
+		if orden == "top" {
+			siguiente = utils.CodificarCursorConteo(orden, ultimo.TotalLikes, ultimo.ID)
+		} else {
+			siguiente = utils.CodificarCursorFecha(orden, ultimo.Fecha, ultimo.ID)
+		}
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": comentarios, "nextCursor": siguiente, "total": total})
+}
+
+// EditarComentario modifica el contenido de un comentario. Solo puede hacerlo su autor.
+func EditarComentario(c echo.Context) error {
+	comentario, ok := comentarioDeRuta(c)
+	if !ok {
+		return nil
+	}
+
+	var body struct {
+		AutorID   string `json:"autorId"`
+		Contenido string `json:"contenido"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Error al parsear el cuerpo"})
+	}
+	autorID, err := primitive.ObjectIDFromHex(body.AutorID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "autorId inválido"})
+	}
+	if comentario.AutorID != autorID {
+		return c.JSON(http.StatusForbidden, echo.Map{"message": "Solo el autor puede editar el comentario"})
+	}
+	if strings.TrimSpace(body.Contenido) == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "El comentario no puede estar vacío"})
+	}
+	if body.Contenido == comentario.Contenido {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "No hay cambios que guardar"})
+	}
+
+	menciones, err := utils.ResolverMenciones(body.Contenido, autorID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al resolver menciones"})
+	}
+	anterior := *comentario
+	err = config.GetCollection("comentarios").FindOneAndUpdate(context.TODO(),
+		bson.M{"_id": comentario.ID},
+		bson.M{"$set": bson.M{
+			"contenido":    body.Contenido,
+			"idioma":       utils.DetectarIdioma(body.Contenido),
+			"menciones":    menciones,
+			"editado":      true,
+			"fechaEdicion": time.Now(),
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(comentario)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al actualizar el comentario"})
+	}
+
+	sumarTagsAlPost(comentario.PostID, autorID, tagsAgregados(
+		utils.ExtraerHashtags(anterior.Contenido),
+		utils.ExtraerHashtags(comentario.Contenido),
+	))
+	utils.NotificarMenciones(comentario.Menciones, autorID, comentario.PostID, "Te mencionó en un comentario", anterior.Menciones)
+
+	return c.JSON(http.StatusOK, comentario)
+}
+
+// EliminarComentario borra un comentario. Pueden hacerlo su autor, el autor del
+// post y los moderadores. Si el comentario tiene respuestas queda como marcador
+// vacío (eliminado: true) para que el hilo siga siendo legible.
+func EliminarComentario(c echo.Context) error {
+	comentario, ok := comentarioDeRuta(c)
+	if !ok {
+		return nil
+	}
+
+	var body struct {
+		UsuarioID string `json:"usuarioId"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Datos inválidos"})
+	}
+	usuarioID, err := primitive.ObjectIDFromHex(body.UsuarioID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "usuarioId inválido"})
+	}
+
+	var post models.Post
+	err = config.GetCollection("posts").FindOne(context.TODO(), bson.M{"_id": comentario.PostID}).Decode(&post)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "Post no encontrado"})
+	}
+
+	var motivo string
+	switch {
+	case usuarioID == comentario.AutorID:
+		motivo = "autor"
+	case usuarioID == post.AutorID:
+		motivo = "autorPost"
+	default:
+		moderador, err := utils.EsModerador(usuarioID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar permisos"})
+		}
+		if !moderador {
+			return c.JSON(http.StatusForbidden, echo.Map{"message": "No puedes eliminar este comentario"})
+		}
+		motivo = "moderacion"
+	}
+
+	// Si tiene respuestas queda un marcador en su lugar; en ambos casos sus
+	// reacciones se borran, porque del marcador no se puede reaccionar ni verlas
+	collection := config.GetCollection("comentarios")
+	if comentario.TotalRespuestas > 0 {
+		_, err = collection.UpdateOne(context.TODO(), bson.M{"_id": comentario.ID}, bson.M{
+			"$set":   bson.M{"eliminado": true, "eliminadoPor": motivo, "contenido": "", "totalLikes": 0},
+			"$unset": bson.M{"menciones": "", "reacciones": ""},
+		})
+	} else {
+		_, err = collection.DeleteOne(context.TODO(), bson.M{"_id": comentario.ID})
+	}
+	if err == nil {
+		_, err = config.GetCollection("reacciones").DeleteMany(context.TODO(), utils.FiltroReaccion(comentario.PostID, &comentario.ID))
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al eliminar el comentario"})
+	}
+	utils.IncrementarContador(comentario.PostID, "totalComentarios", -1)
+	if comentario.TotalRespuestas == 0 {
+		quitarRespuesta(comentario.ParentID)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"message": "Comentario eliminado"})
+}
+
+// BloquearComentarios desactiva los comentarios nuevos en un post.
+func BloquearComentarios(c echo.Context) error {
+	return cambiarBloqueoComentarios(c, true)
+}
+
+// DesbloquearComentarios vuelve a permitir comentarios en un post.
+func DesbloquearComentarios(c echo.Context) error {
+	return cambiarBloqueoComentarios(c, false)
+}
+
+// cambiarBloqueoComentarios aplica el bloqueo de comentarios; solo el autor del post
+// o un moderador pueden hacerlo.
+func cambiarBloqueoComentarios(c echo.Context, bloquear bool) error {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID del post inválido"})
+	}
+	var body struct {
+		UsuarioID string `json:"usuarioId"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Datos inválidos"})
+	}
+	usuarioID, err := primitive.ObjectIDFromHex(body.UsuarioID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "usuarioId inválido"})
+	}
+
+	collection := config.GetCollection("posts")
+	var post models.Post
+	err = collection.FindOne(context.TODO(), bson.M{"_id": postID}).Decode(&post)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "Post no encontrado"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar el post"})
+	}
+	if post.AutorID != usuarioID {
+		moderador, err := utils.EsModerador(usuarioID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar permisos"})
+		}
+		if !moderador {
+			return c.JSON(http.StatusForbidden, echo.Map{"message": "Solo el autor o un moderador pueden cambiar esto"})
+		}
+	}
+
+	_, err = collection.UpdateOne(context.TODO(),
+		bson.M{"_id": postID},
+		bson.M{"$set": bson.M{"comentariosBloqueados": bloquear}},
+	)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al actualizar el post"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"comentariosBloqueados": bloquear})
+}
+
+// comentarioDeRuta busca el comentario :commentId del post :id. Si no existe (o ya
+// fue eliminado) responde al cliente y devuelve ok = false.
+func comentarioDeRuta(c echo.Context) (*models.Comentario, bool) {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, echo.Map{"message": "ID del post inválido"})
+		return nil, false
+	}
+	comentarioID, err := primitive.ObjectIDFromHex(c.Param("commentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, echo.Map{"message": "ID del comentario inválido"})
+		return nil, false
+	}
+
+	var comentario models.Comentario
+	err = config.GetCollection("comentarios").FindOne(context.TODO(), bson.M{
+		"_id":       comentarioID,
+		"postId":    postID,
+		"eliminado": bson.M{"$ne": true},
+	}).Decode(&comentario)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, echo.Map{"message": "Comentario no encontrado"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar el comentario"})
+		return nil, false
+	}
+	return &comentario, true
+}
+
+// quitarRespuesta descuenta una respuesta del comentario padre. Los marcadores de
+// comentarios eliminados que se quedan sin respuestas se borran, subiendo por el hilo.
+func quitarRespuesta(parentID *primitive.ObjectID) {
+	collection := config.GetCollection("comentarios")
+	for parentID != nil {
+		var padre models.Comentario
+		err := collection.FindOneAndUpdate(context.TODO(),
+			bson.M{"_id": *parentID},
+			bson.M{"$inc": bson.M{"totalRespuestas": -1}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&padre)
+		if err != nil {
+			if err != mongo.ErrNoDocuments {
+				log.Println("Error actualizando totalRespuestas del comentario:", err)
+			}
+			return
+		}
+		if !padre.Eliminado || padre.TotalRespuestas > 0 {
+			return
+		}
+		if _, err := collection.DeleteOne(context.TODO(), bson.M{"_id": padre.ID}); err != nil {
+			log.Println("Error borrando comentario eliminado sin respuestas:", err)
+			return
+		}
+		// Los marcadores anteriores a borrar las reacciones al eliminar aún las tienen
+		if _, err := config.GetCollection("reacciones").DeleteMany(context.TODO(), utils.FiltroReaccion(padre.PostID, &padre.ID)); err != nil {
+			log.Println("Error borrando las reacciones del comentario eliminado:", err)
+		}
+		parentID = padre.ParentID
+	}
+}
+
+// sumarTagsAlPost agrega al post los hashtags usados en uno de sus comentarios.
+func sumarTagsAlPost(postID, autorID primitive.ObjectID, hashtags []string) {
+	if len(hashtags) == 0 {
+		return
+	}
+	_, err := config.GetCollection("posts").UpdateOne(context.TODO(),
+		bson.M{"_id": postID},
+		bson.M{"$addToSet": bson.M{"tags": bson.M{"$each": hashtags}}},
+	)
+	if err != nil {
+		log.Println("Error agregando hashtags del comentario al post:", err)
+	}
+	utils.RegistrarUsoTags(hashtags, postID, autorID, "comentario")
+}
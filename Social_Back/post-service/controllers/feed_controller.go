@@ -0,0 +1,434 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"post-service/config"
+	"post-service/models"
+	"post-service/utils"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// VerFeed devuelve el timeline de ?viewerId=: sus posts y los de quienes sigue,
+// del más reciente al más antiguo, paginado con el mismo cursor que GET /posts.
+func VerFeed(c echo.Context) error {
+	viewerID, err := viewerDesdeQuery(c)
+	if err != nil || viewerID.IsZero() {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Se requiere un viewerId válido"})
+	}
+	cursor, err := utils.DecodificarCursor(c.QueryParam("cursor"))
+	if err != nil || (cursor != nil && cursor.Orden != "") {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Cursor inválido"})
+	}
+	limite := utils.ParsearLimite(c.QueryParam("limit"), 20, 50)
+
+	silenciados, err := utils.UsuariosSilenciados(viewerID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al preparar el feed"})
+	}
+	materializado, err := utils.TimelineMaterializado(viewerID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al preparar el feed"})
+	}
+	visibles, err := utils.FiltroVisibles(viewerID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al preparar el feed"})
+	}
+	// Los borradores y programados del viewer no aparecen en su feed
+	visibles = bson.M{"$and": []bson.M{visibles, utils.FiltroPublicados()}}
+
+	// Se pide uno extra para saber si existe una página siguiente
+	posts := []models.Post{}
+	if materializado {
+		posts, err = feedDesdeTimeline(viewerID, silenciados, visibles, cursor, limite+1)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer el feed"})
+		}
+	}
+
+	// Fan-out-on-read: todo el feed si no hay timeline materializado, o lo que
+	// quede más allá de las entradas precalculadas
+	if len(posts) < limite+1 {
+		desde := cursor
+		if len(posts) > 0 {
+			ultimo := posts[len(posts)-1]
+			desde = &utils.Cursor{Fecha: ultimo.FechaCreado, ID: ultimo.ID}
+		}
+		resto, err := feedAlVuelo(viewerID, silenciados, visibles, desde, limite+1-len(posts))
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer el feed"})
+		}
+		posts = append(posts, resto...)
+	}
+
+	var siguiente string
+	if len(posts) > limite {
+		posts = posts[:limite]
+		ultimo := posts[limite-1]
+		siguiente = utils.CodificarCursor(ultimo.FechaCreado, ultimo.ID)
+	}
+	if err := completarPosts(viewerID, punteros(posts)...); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al completar los posts"})
+	}
+	posts = sinRepostsOcultos(posts)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": posts, "nextCursor": siguiente})
+}
+
+// feedAlVuelo lee directamente de posts los de los seguidos del viewer y los suyos.
+// Con el índice {autorId, estado, fechaCreado, _id} Mongo combina un rango ya
+// ordenado por autor, pero el costo crece con la cantidad de seguidos: por eso a
+// partir de FEED_FANOUT_UMBRAL el feed sale del timeline materializado y esto solo
+// completa lo que queda fuera de él. visibles selecciona los posts publicados que
+// el viewer puede ver.
+func feedAlVuelo(viewerID primitive.ObjectID, silenciados []primitive.ObjectID, visibles bson.M, cursor *utils.Cursor, n int) ([]models.Post, error) {
+	ctx := context.TODO()
+	valores, err := config.GetCollection("follows").Distinct(ctx, "seguidoId", bson.M{"seguidorId": viewerID})
+	if err != nil {
+		return nil, err
+	}
+
+	silenciado := map[primitive.ObjectID]bool{}
+	for _, id := range silenciados {
+		silenciado[id] = true
+	}
+	autores := []primitive.ObjectID{viewerID}
+	for _, id := range utils.IDsDeDistinct(valores) {
+		if !silenciado[id] {
+			autores = append(autores, id)
+		}
+	}
+
+	filtro := bson.M{"$and": []bson.M{{"autorId": bson.M{"$in": autores}}, visibles}}
+	if cursor != nil {
+		filtro = bson.M{"$and": []bson.M{filtro, utils.FiltroDespuesDe("fechaCreado", cursor)}}
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "fechaCreado", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(n)).
+		SetProjection(utils.SinTextoAdjuntos)
+	cur, err := config.GetCollection("posts").Find(ctx, filtro, opts)
+	if err != nil {
+		return nil, err
+	}
+	posts := []models.Post{}
+	err = cur.All(ctx, &posts)
+	return posts, err
+}
+
+// feedDesdeTimeline lee el timeline precalculado (fan-out-on-write) del viewer y
+// carga los posts referenciados que sigue pudiendo ver, conservando el orden.
+func feedDesdeTimeline(viewerID primitive.ObjectID, silenciados []primitive.ObjectID, visibles bson.M, cursor *utils.Cursor, n int) ([]models.Post, error) {
+	ctx := context.TODO()
+	filtro := bson.M{"usuarioId": viewerID}
+	if len(silenciados) > 0 {
+		filtro["autorId"] = bson.M{"$nin": silenciados}
+	}
+	if cursor != nil {
+		filtro["$or"] = []bson.M{
+			{"fechaCreado": bson.M{"$lt": cursor.Fecha}},
+			{"fechaCreado": cursor.Fecha, "postId": bson.M{"$lt": cursor.ID}},
+		}
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "fechaCreado", Value: -1}, {Key: "postId", Value: -1}}).
+		SetLimit(int64(n))
+	cur, err := config.GetCollection("timelines").Find(ctx, filtro, opts)
+	if err != nil {
+		return nil, err
+	}
+	var entradas []models.TimelineEntrada
+	if err := cur.All(ctx, &entradas); err != nil {
+		return nil, err
+	}
+	if len(entradas) == 0 {
+		return []models.Post{}, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(entradas))
+	for _, e := range entradas {
+		ids = append(ids, e.PostID)
+	}
+	cur, err = config.GetCollection("posts").Find(ctx, bson.M{"$and": []bson.M{{"_id": bson.M{"$in": ids}}, visibles}},
+		options.Find().SetProjection(utils.SinTextoAdjuntos))
+	if err != nil {
+		return nil, err
+	}
+	var encontrados []models.Post
+	if err := cur.All(ctx, &encontrados); err != nil {
+		return nil, err
+	}
+	porID := make(map[primitive.ObjectID]models.Post, len(encontrados))
+	for _, p := range encontrados {
+		porID[p.ID] = p
+	}
+
+	// Se omiten entradas de posts que ya no existen o que ya no puede ver
+	posts := make([]models.Post, 0, len(ids))
+	for _, id := range ids {
+		if p, ok := porID[id]; ok {
+			posts = append(posts, p)
+		}
+	}
+	return posts, nil
+}
+
+// Límites del feed "para ti": de dónde salen los candidatos y qué historial se usa.
+const (
+	ventanaCandidatosParaTi = 7 * 24 * time.Hour
+	maxCandidatosParaTi     = 500
+	ventanaHistorialParaTi  = 90 * 24 * time.Hour
+)
+
+// VerFeedParaTi devuelve un feed rankeado para ?viewerId= combinando recencia,
+// velocidad de likes/comentarios, afinidad con el autor, intereses y diversidad.
+// Con ?debug=true cada post incluye el desglose de su puntaje.
+//
+// Los puntajes cambian de un pedido a otro (la recencia decae, llegan likes), así
+// que el ranking se calcula solo para la primera página y se guarda en
+// "rankings_para_ti"; las siguientes recorren ese mismo orden a través del cursor.
+func VerFeedParaTi(c echo.Context) error {
+	viewerID, err := viewerDesdeQuery(c)
+	if err != nil || viewerID.IsZero() {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Se requiere un viewerId válido"})
+	}
+	cursor, err := utils.DecodificarCursor(c.QueryParam("cursor"))
+	if err != nil || (cursor != nil && cursor.Orden != "para-ti") {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Cursor inválido"})
+	}
+	limite := utils.ParsearLimite(c.QueryParam("limit"), 20, 50)
+	debug := c.QueryParam("debug") == "true"
+	ctx := context.TODO()
+
+	visibles, err := utils.FiltroListado(viewerID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al preparar el feed"})
+	}
+	pesos := utils.CargarPesosRanking()
+
+	var ranking models.RankingParaTi
+	desde := 0
+	if cursor == nil {
+		ranking, err = rankearParaTi(viewerID, visibles, pesos)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al rankear el feed"})
+		}
+	} else {
+		err = config.GetCollection("rankings_para_ti").FindOne(ctx, bson.M{"_id": cursor.ID, "usuarioId": viewerID}).Decode(&ranking)
+		if err == mongo.ErrNoDocuments {
+			return c.JSON(http.StatusBadRequest, echo.Map{"message": "El cursor venció, vuelve a cargar el feed"})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer el feed"})
+		}
+		desde = min(max(int(cursor.Valor), 0), len(ranking.Posts))
+	}
+	hasta := min(desde+limite, len(ranking.Posts))
+	entradas := ranking.Posts[desde:hasta]
+
+	// Los posts se leen de nuevo: pueden haberse eliminado u ocultado desde el ranking
+	ids := make([]primitive.ObjectID, len(entradas))
+	for i, e := range entradas {
+		ids[i] = e.PostID
+	}
+	cur, err := config.GetCollection("posts").Find(ctx, bson.M{"$and": []bson.M{{"_id": bson.M{"$in": ids}}, visibles}},
+		options.Find().SetProjection(utils.SinTextoAdjuntos))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer el feed"})
+	}
+	var encontrados []models.Post
+	if err := cur.All(ctx, &encontrados); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer el feed"})
+	}
+	porID := make(map[primitive.ObjectID]models.Post, len(encontrados))
+	for _, p := range encontrados {
+		porID[p.ID] = p
+	}
+
+	pagina := make([]models.PostRankeado, 0, len(entradas))
+	for _, e := range entradas {
+		p, ok := porID[e.PostID]
+		if !ok {
+			continue
+		}
+		rankeado := models.PostRankeado{Post: p, Puntaje: e.Puntaje}
+		if debug {
+			desglose := e.Desglose
+			rankeado.Desglose = &desglose
+		}
+		pagina = append(pagina, rankeado)
+	}
+	enPagina := make([]*models.Post, len(pagina))
+	for i := range pagina {
+		enPagina[i] = &pagina[i].Post
+	}
+	if err := completarPosts(viewerID, enPagina...); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al completar los posts"})
+	}
+
+	var siguiente string
+	if hasta < len(ranking.Posts) {
+		siguiente = utils.CodificarCursorConteo("para-ti", int64(hasta), ranking.ID)
+	}
+
+	respuesta := echo.Map{"data": pagina, "nextCursor": siguiente}
+	if debug {
+		respuesta["pesos"] = pesos
+	}
+	return c.JSON(http.StatusOK, respuesta)
+}
+
+// rankearParaTi puntúa los candidatos del feed "para ti" de viewerID y guarda el
+// orden resultante para paginarlo.
+func rankearParaTi(viewerID primitive.ObjectID, visibles bson.M, pesos utils.PesosRanking) (models.RankingParaTi, error) {
+	ctx := context.TODO()
+
+	// Candidatos: posts recientes de otros autores visibles para el viewer (los
+	// reposts no, su original ya es candidato)
+	ahora := time.Now()
+	opts := options.Find().
+		SetSort(bson.D{{Key: "fechaCreado", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(maxCandidatosParaTi).
+		SetProjection(utils.SinTextoAdjuntos)
+	cur, err := config.GetCollection("posts").Find(ctx, bson.M{"$and": []bson.M{
+		{
+			"fechaCreado": bson.M{"$gte": ahora.Add(-ventanaCandidatosParaTi)},
+			"autorId":     bson.M{"$ne": viewerID},
+			"repostDe":    nil,
+		},
+		visibles,
+	}}, opts)
+	if err != nil {
+		return models.RankingParaTi{}, err
+	}
+	var candidatos []models.Post
+	if err := cur.All(ctx, &candidatos); err != nil {
+		return models.RankingParaTi{}, err
+	}
+
+	historial, err := historialDeInteres(viewerID, ahora.Add(-ventanaHistorialParaTi))
+	if err != nil {
+		return models.RankingParaTi{}, err
+	}
+
+	rankeados := make([]models.PostRankeado, len(candidatos))
+	desgloses := make([]models.DesglosePuntaje, len(candidatos))
+	for i, p := range candidatos {
+		rankeados[i] = models.PostRankeado{Post: p}
+		desgloses[i] = utils.PuntuarPost(pesos, utils.SenalesPost{
+			EdadHoras:     ahora.Sub(p.FechaCreado).Hours(),
+			Likes:         p.TotalLikes,
+			Comentarios:   p.TotalComentarios,
+			Interacciones: historial.interaccionesPorAutor[p.AutorID],
+			SigueAutor:    historial.seguidos[p.AutorID],
+			Coincidencia:  historial.coincidencia(p),
+		})
+	}
+	utils.Diversificar(rankeados, desgloses, pesos.PenalizacionAutor)
+
+	ranking := models.RankingParaTi{
+		ID:        primitive.NewObjectID(),
+		UsuarioID: viewerID,
+		Posts:     make([]models.EntradaRanking, len(rankeados)),
+		Fecha:     ahora,
+	}
+	for i, r := range rankeados {
+		ranking.Posts[i] = models.EntradaRanking{PostID: r.ID, Puntaje: r.Puntaje, Desglose: desgloses[i]}
+	}
+	_, err = config.GetCollection("rankings_para_ti").InsertOne(ctx, ranking)
+	return ranking, err
+}
+
+// historialInteres resume con qué autores y temas interactuó el viewer.
+type historialInteres struct {
+	seguidos              map[primitive.ObjectID]bool
+	interaccionesPorAutor map[primitive.ObjectID]int
+	tags                  map[string]bool
+	categorias            map[string]bool
+}
+
+// coincidencia devuelve entre 0 y 1 qué tanto coinciden los tags y la categoría
+// del post con los del historial.
+func (h historialInteres) coincidencia(p models.Post) float64 {
+	total := 0.0
+	if len(p.Tags) > 0 {
+		comunes := 0
+		for _, t := range p.Tags {
+			if h.tags[t] {
+				comunes++
+			}
+		}
+		total += 0.7 * float64(comunes) / float64(len(p.Tags))
+	}
+	if p.Categoria != "" && h.categorias[p.Categoria] {
+		total += 0.3
+	}
+	return total
+}
+
+// historialDeInteres reúne los seguidos del viewer y, a partir de sus likes,
+// comentarios y posts desde la fecha indicada, las interacciones por autor y los
+// tags/categorías que le interesan.
+func historialDeInteres(viewerID primitive.ObjectID, desde time.Time) (historialInteres, error) {
+	ctx := context.TODO()
+	h := historialInteres{
+		seguidos:              map[primitive.ObjectID]bool{},
+		interaccionesPorAutor: map[primitive.ObjectID]int{},
+		tags:                  map[string]bool{},
+		categorias:            map[string]bool{},
+	}
+
+	valores, err := config.GetCollection("follows").Distinct(ctx, "seguidoId", bson.M{"seguidorId": viewerID})
+	if err != nil {
+		return h, err
+	}
+	for _, id := range utils.IDsDeDistinct(valores) {
+		h.seguidos[id] = true
+	}
+
+	// Posts con los que interactuó: reacciones, comentarios y los propios
+	postIDs := []primitive.ObjectID{}
+	for _, fuente := range []struct{ coleccion, campoUsuario string }{
+		{"reacciones", "usuarioId"},
+		{"comentarios", "autorId"},
+	} {
+		valores, err := config.GetCollection(fuente.coleccion).Distinct(ctx, "postId", bson.M{
+			fuente.campoUsuario: viewerID,
+			"fecha":             bson.M{"$gte": desde},
+		})
+		if err != nil {
+			return h, err
+		}
+		postIDs = append(postIDs, utils.IDsDeDistinct(valores)...)
+	}
+
+	cur, err := config.GetCollection("posts").Find(ctx, bson.M{"$or": []bson.M{
+		{"_id": bson.M{"$in": postIDs}},
+		{"autorId": viewerID, "fechaCreado": bson.M{"$gte": desde}},
+	}}, options.Find().SetProjection(bson.M{"autorId": 1, "tags": 1, "categoria": 1}))
+	if err != nil {
+		return h, err
+	}
+	var posts []models.Post
+	if err := cur.All(ctx, &posts); err != nil {
+		return h, err
+	}
+	for _, p := range posts {
+		if p.AutorID != viewerID {
+			h.interaccionesPorAutor[p.AutorID]++
+		}
+		for _, t := range p.Tags {
+			h.tags[t] = true
+		}
+		if p.Categoria != "" {
+			h.categorias[p.Categoria] = true
+		}
+	}
+	return h, nil
+}
@@ -0,0 +1,258 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"post-service/config"
+	"post-service/models"
+	"post-service/utils"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// crearSolicitudSeguimiento registra una solicitud pendiente hacia una cuenta privada
+// y notifica al dueño de la cuenta.
+func crearSolicitudSeguimiento(c echo.Context, seguidorID, seguidoID primitive.ObjectID) error {
+	solicitud := models.FollowRequest{
+		ID:         primitive.NewObjectID(),
+		SeguidorID: seguidorID,
+		SeguidoID:  seguidoID,
+		Fecha:      time.Now(),
+	}
+
+	_, err := config.GetCollection("follow_requests").InsertOne(context.TODO(), solicitud)
+	if mongo.IsDuplicateKeyError(err) {
+		return c.JSON(http.StatusConflict, echo.Map{"message": "Ya enviaste una solicitud a este usuario"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al guardar la solicitud"})
+	}
+
+	log.Println("Solicitud de seguimiento:", seguidorID.Hex(), "->", seguidoID.Hex())
+	utils.CrearNotificacion("solicitud_seguimiento", seguidorID, seguidoID, "Quiere seguirte", nil)
+
+	return c.JSON(http.StatusAccepted, echo.Map{"message": "Solicitud enviada", "solicitud": solicitud})
+}
+
+// VerSolicitudesSeguimiento lista, paginadas, las solicitudes pendientes que recibió el usuario.
+func VerSolicitudesSeguimiento(c echo.Context) error {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de usuario inválido"})
+	}
+
+	cursor, err := utils.DecodificarCursor(c.QueryParam("cursor"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Cursor inválido"})
+	}
+	limite := utils.ParsearLimite(c.QueryParam("limit"), 20, 100)
+
+	match := bson.M{"seguidoId": id}
+	if cursor != nil {
+		match = bson.M{"$and": []bson.M{match, utils.FiltroDespuesDe("fecha", cursor)}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "fecha", Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$limit", Value: limite + 1}},
+	}
+	pipeline = append(pipeline, etapasUsuarioPublico("seguidorId", "usuario")...)
+
+	cur, err := config.GetCollection("follow_requests").Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar solicitudes"})
+	}
+	defer cur.Close(context.TODO())
+
+	solicitudes := []models.FollowRequestDetalle{}
+	if err := cur.All(context.TODO(), &solicitudes); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer solicitudes"})
+	}
+
+	var siguiente string
+	if len(solicitudes) > limite {
+		solicitudes = solicitudes[:limite]
+		ultima := solicitudes[limite-1]
+		siguiente = utils.CodificarCursor(ultima.Fecha, ultima.ID)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": solicitudes, "nextCursor": siguiente})
+}
+
+// AceptarSolicitud convierte una solicitud pendiente en follow y notifica a quien la envió.
+func AceptarSolicitud(c echo.Context) error {
+	solicitud, ok := solicitudDelUsuario(c)
+	if !ok {
+		return nil
+	}
+
+	if err := aprobarSolicitud(*solicitud); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al aceptar la solicitud"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"message": "Solicitud aceptada"})
+}
+
+// RechazarSolicitud elimina una solicitud pendiente sin avisar a quien la envió.
+func RechazarSolicitud(c echo.Context) error {
+	solicitud, ok := solicitudDelUsuario(c)
+	if !ok {
+		return nil
+	}
+
+	_, err := config.GetCollection("follow_requests").DeleteOne(context.TODO(), bson.M{"_id": solicitud.ID})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al rechazar la solicitud"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"message": "Solicitud rechazada"})
+}
+
+// CancelarSolicitud permite a quien envió la solicitud retirarla antes de que se responda.
+func CancelarSolicitud(c echo.Context) error {
+	seguidoID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de seguido inválido"})
+	}
+
+	var body struct {
+		SeguidorID string `json:"seguidorId"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Datos inválidos"})
+	}
+	seguidorID, err := primitive.ObjectIDFromHex(body.SeguidorID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de seguidor inválido"})
+	}
+
+	result, err := config.GetCollection("follow_requests").DeleteOne(context.TODO(), bson.M{
+		"seguidorId": seguidorID,
+		"seguidoId":  seguidoID,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al cancelar la solicitud"})
+	}
+	if result.DeletedCount == 0 {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "Solicitud no encontrada"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"message": "Solicitud cancelada"})
+}
+
+// ObtenerPrivacidad devuelve los ajustes de privacidad del usuario.
+func ObtenerPrivacidad(c echo.Context) error {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de usuario inválido"})
+	}
+
+	ajustes, err := utils.ObtenerAjustes(id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer la privacidad"})
+	}
+
+	return c.JSON(http.StatusOK, ajustes)
+}
+
+// ActualizarPrivacidad cambia la cuenta entre pública y privada. Al volverla pública
+// se aceptan automáticamente las solicitudes que estaban pendientes.
+func ActualizarPrivacidad(c echo.Context) error {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de usuario inválido"})
+	}
+
+	var body struct {
+		CuentaPrivada *bool `json:"cuentaPrivada"`
+	}
+	if err := c.Bind(&body); err != nil || body.CuentaPrivada == nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Se requiere cuentaPrivada"})
+	}
+
+	_, err = config.GetCollection("ajustes").UpdateOne(context.TODO(),
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"cuentaPrivada": *body.CuentaPrivada}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al guardar la privacidad"})
+	}
+
+	if !*body.CuentaPrivada {
+		cur, err := config.GetCollection("follow_requests").Find(context.TODO(), bson.M{"seguidoId": id})
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al aceptar solicitudes pendientes"})
+		}
+		var pendientes []models.FollowRequest
+		if err := cur.All(context.TODO(), &pendientes); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al aceptar solicitudes pendientes"})
+		}
+		for _, s := range pendientes {
+			if err := aprobarSolicitud(s); err != nil {
+				log.Println("Error aceptando solicitud", s.ID.Hex(), ":", err)
+			}
+		}
+	}
+
+	return c.JSON(http.StatusOK, models.AjustesUsuario{UsuarioID: id, CuentaPrivada: *body.CuentaPrivada})
+}
+
+// solicitudDelUsuario carga la solicitud :requestId y verifica que vaya dirigida al usuario :id.
+// Si algo falla ya respondió y devuelve ok=false.
+func solicitudDelUsuario(c echo.Context) (*models.FollowRequest, bool) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de usuario inválido"})
+		return nil, false
+	}
+	solicitudID, err := primitive.ObjectIDFromHex(c.Param("requestId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de solicitud inválido"})
+		return nil, false
+	}
+
+	var solicitud models.FollowRequest
+	err = config.GetCollection("follow_requests").FindOne(context.TODO(),
+		bson.M{"_id": solicitudID, "seguidoId": userID}).Decode(&solicitud)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, echo.Map{"message": "Solicitud no encontrada"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar la solicitud"})
+		return nil, false
+	}
+
+	return &solicitud, true
+}
+
+// aprobarSolicitud crea el follow correspondiente, elimina la solicitud y notifica al solicitante.
+func aprobarSolicitud(solicitud models.FollowRequest) error {
+	follow := models.Follow{
+		ID:         primitive.NewObjectID(),
+		SeguidorID: solicitud.SeguidorID,
+		SeguidoID:  solicitud.SeguidoID,
+		Fecha:      time.Now(),
+	}
+	_, err := config.GetCollection("follows").InsertOne(context.TODO(), follow)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	if _, err := config.GetCollection("follow_requests").DeleteOne(context.TODO(), bson.M{"_id": solicitud.ID}); err != nil {
+		return err
+	}
+
+	log.Println("Solicitud aceptada:", solicitud.SeguidorID.Hex(), "->", solicitud.SeguidoID.Hex())
+	go utils.AgregarAutorATimeline(solicitud.SeguidorID, solicitud.SeguidoID)
+	utils.CrearNotificacion("follow_aceptado", solicitud.SeguidoID, solicitud.SeguidorID, "Aceptó tu solicitud de seguimiento", nil)
+	return nil
+}
@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"post-service/config"
+	"post-service/models"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	maxGrupos        = 50   // grupos por usuario
+	maxMiembrosGrupo = 1000 // usuarios por grupo
+	maxNombreGrupo   = 50   // runas
+)
+
+// VerGrupos lista los grupos de :id con sus miembros. Solo los ve su dueño.
+func VerGrupos(c echo.Context) error {
+	duenoID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID inválido"})
+	}
+	cur, err := config.GetCollection("grupos").Find(context.TODO(),
+		bson.M{"duenoId": duenoID},
+		options.Find().SetSort(bson.D{{Key: "nombre", Value: 1}}),
+	)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar los grupos"})
+	}
+	grupos := []models.Grupo{}
+	if err := cur.All(context.TODO(), &grupos); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer los grupos"})
+	}
+	return c.JSON(http.StatusOK, echo.Map{"data": grupos})
+}
+
+// CrearGrupo crea el grupo {nombre, miembros: [ids]} de :id.
+func CrearGrupo(c echo.Context) error {
+	duenoID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID inválido"})
+	}
+	var body struct {
+		Nombre   string   `json:"nombre"`
+		Miembros []string `json:"miembros"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Error al parsear el cuerpo JSON"})
+	}
+	nombre, ok := nombreGrupoValido(c, body.Nombre)
+	if !ok {
+		return nil
+	}
+	if len(body.Miembros) > maxMiembrosGrupo {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "El grupo supera el máximo de miembros"})
+	}
+	miembros := make([]primitive.ObjectID, 0, len(body.Miembros))
+	vistos := map[primitive.ObjectID]bool{}
+	for _, m := range body.Miembros {
+		id, err := primitive.ObjectIDFromHex(m)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de miembro inválido"})
+		}
+		if !vistos[id] {
+			vistos[id] = true
+			miembros = append(miembros, id)
+		}
+	}
+
+	collection := config.GetCollection("grupos")
+	total, err := collection.CountDocuments(context.TODO(), bson.M{"duenoId": duenoID})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al contar los grupos"})
+	}
+	if total >= maxGrupos {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Alcanzaste el máximo de grupos"})
+	}
+
+	grupo := models.Grupo{
+		ID:       primitive.NewObjectID(),
+		DuenoID:  duenoID,
+		Nombre:   nombre,
+		Miembros: miembros,
+		Fecha:    time.Now(),
+	}
+	_, err = collection.InsertOne(context.TODO(), grupo)
+	if mongo.IsDuplicateKeyError(err) {
+		return c.JSON(http.StatusConflict, echo.Map{"message": "Ya tienes un grupo con ese nombre"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al crear el grupo"})
+	}
+	return c.JSON(http.StatusCreated, grupo)
+}
+
+// RenombrarGrupo cambia el nombre {nombre} del grupo :grupoId de :id.
+func RenombrarGrupo(c echo.Context) error {
+	duenoID, grupoID, ok := idsGrupo(c)
+	if !ok {
+		return nil
+	}
+	var body struct {
+		Nombre string `json:"nombre"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Error al parsear el cuerpo JSON"})
+	}
+	nombre, ok := nombreGrupoValido(c, body.Nombre)
+	if !ok {
+		return nil
+	}
+
+	var grupo models.Grupo
+	err := config.GetCollection("grupos").FindOneAndUpdate(context.TODO(),
+		bson.M{"_id": grupoID, "duenoId": duenoID},
+		bson.M{"$set": bson.M{"nombre": nombre}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&grupo)
+	switch {
+	case err == mongo.ErrNoDocuments:
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "Grupo no encontrado"})
+	case mongo.IsDuplicateKeyError(err):
+		return c.JSON(http.StatusConflict, echo.Map{"message": "Ya tienes un grupo con ese nombre"})
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al renombrar el grupo"})
+	}
+	return c.JSON(http.StatusOK, grupo)
+}
+
+// EliminarGrupo borra el grupo :grupoId de :id. Sus posts quedan visibles solo
+// para el autor hasta que les cambie la visibilidad.
+func EliminarGrupo(c echo.Context) error {
+	duenoID, grupoID, ok := idsGrupo(c)
+	if !ok {
+		return nil
+	}
+	res, err := config.GetCollection("grupos").DeleteOne(context.TODO(), bson.M{"_id": grupoID, "duenoId": duenoID})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al eliminar el grupo"})
+	}
+	if res.DeletedCount == 0 {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "Grupo no encontrado"})
+	}
+	return c.JSON(http.StatusOK, echo.Map{"message": "Grupo eliminado"})
+}
+
+// AgregarMiembro suma :usuarioId al grupo :grupoId de :id.
+func AgregarMiembro(c echo.Context) error {
+	duenoID, grupoID, ok := idsGrupo(c)
+	if !ok {
+		return nil
+	}
+	miembroID, err := primitive.ObjectIDFromHex(c.Param("usuarioId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de miembro inválido"})
+	}
+
+	// El límite se verifica en el mismo update para no pasarse con pedidos simultáneos
+	res, err := config.GetCollection("grupos").UpdateOne(context.TODO(),
+		bson.M{"_id": grupoID, "duenoId": duenoID, "miembros." + strconv.Itoa(maxMiembrosGrupo-1): bson.M{"$exists": false}},
+		bson.M{"$addToSet": bson.M{"miembros": miembroID}},
+	)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al agregar el miembro"})
+	}
+	if res.MatchedCount == 0 {
+		existe, err := config.GetCollection("grupos").CountDocuments(context.TODO(), bson.M{"_id": grupoID, "duenoId": duenoID})
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar el grupo"})
+		}
+		if existe == 0 {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "Grupo no encontrado"})
+		}
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "El grupo supera el máximo de miembros"})
+	}
+	return c.JSON(http.StatusOK, echo.Map{"message": "Miembro agregado"})
+}
+
+// QuitarMiembro saca a :usuarioId del grupo :grupoId de :id.
+func QuitarMiembro(c echo.Context) error {
+	duenoID, grupoID, ok := idsGrupo(c)
+	if !ok {
+		return nil
+	}
+	miembroID, err := primitive.ObjectIDFromHex(c.Param("usuarioId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de miembro inválido"})
+	}
+
+	res, err := config.GetCollection("grupos").UpdateOne(context.TODO(),
+		bson.M{"_id": grupoID, "duenoId": duenoID},
+		bson.M{"$pull": bson.M{"miembros": miembroID}},
+	)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al quitar el miembro"})
+	}
+	if res.MatchedCount == 0 {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "Grupo no encontrado"})
+	}
+	return c.JSON(http.StatusOK, echo.Map{"message": "Miembro quitado"})
+}
+
+// idsGrupo lee :id y :grupoId. Si alguno es inválido responde al cliente y
+// devuelve ok = false.
+func idsGrupo(c echo.Context) (primitive.ObjectID, primitive.ObjectID, bool) {
+	duenoID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, echo.Map{"message": "ID inválido"})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+	grupoID, err := primitive.ObjectIDFromHex(c.Param("grupoId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de grupo inválido"})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+	return duenoID, grupoID, true
+}
+
+// nombreGrupoValido limpia y valida el nombre de un grupo. Si no es válido
+// responde al cliente y devuelve ok = false.
+func nombreGrupoValido(c echo.Context, nombre string) (string, bool) {
+	nombre = strings.TrimSpace(nombre)
+	if nombre == "" || utf8.RuneCountInString(nombre) > maxNombreGrupo {
+		c.JSON(http.StatusBadRequest, echo.Map{"message": "El nombre debe tener entre 1 y 50 caracteres"})
+		return "", false
+	}
+	return nombre, true
+}
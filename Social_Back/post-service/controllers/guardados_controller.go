@@ -0,0 +1,433 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"post-service/config"
+	"post-service/models"
+	"post-service/utils"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	maxColeccionesGuardados = 100 // colecciones por usuario
+	maxNombreColeccion      = 50  // runas
+)
+
+// GuardarPost guarda el post :id para {usuarioId} y, si se indican, lo agrega a
+// sus colecciones {colecciones: [ids]}.
+func GuardarPost(c echo.Context) error {
+	var body struct {
+		UsuarioID   string   `json:"usuarioId"`
+		Colecciones []string `json:"colecciones"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Error al parsear el cuerpo JSON"})
+	}
+	usuarioID, err := primitive.ObjectIDFromHex(body.UsuarioID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de usuario inválido"})
+	}
+	postID, ok := postParaGuardar(c, c.Param("id"), usuarioID)
+	if !ok {
+		return nil
+	}
+	colecciones, ok := coleccionesDelUsuario(c, body.Colecciones, usuarioID)
+	if !ok {
+		return nil
+	}
+
+	if err := utils.GuardarPost(usuarioID, postID, colecciones); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al guardar el post"})
+	}
+	return c.JSON(http.StatusOK, echo.Map{"saved": true})
+}
+
+// QuitarGuardado quita el post :id de los guardados de {usuarioId} (y de todas sus
+// colecciones). No falla si no estaba guardado.
+func QuitarGuardado(c echo.Context) error {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID del post inválido"})
+	}
+	var body struct {
+		UsuarioID string `json:"usuarioId"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Error al parsear el cuerpo JSON"})
+	}
+	usuarioID, err := primitive.ObjectIDFromHex(body.UsuarioID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de usuario inválido"})
+	}
+
+	_, err = config.GetCollection("guardados").DeleteOne(context.TODO(), bson.M{"usuarioId": usuarioID, "postId": postID})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al quitar el post de guardados"})
+	}
+	return c.JSON(http.StatusOK, echo.Map{"saved": false})
+}
+
+// VerGuardados lista, del más reciente al más antiguo, los posts guardados por :id
+// (?coleccion= filtra por una colección). Los posts eliminados o que ya no puede
+// ver se omiten.
+func VerGuardados(c echo.Context) error {
+	usuarioID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID inválido"})
+	}
+	cursor, err := utils.DecodificarCursor(c.QueryParam("cursor"))
+	if err != nil || (cursor != nil && cursor.Orden != "") {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Cursor inválido"})
+	}
+	limite := utils.ParsearLimite(c.QueryParam("limit"), 20, 50)
+
+	filtro := bson.M{"usuarioId": usuarioID}
+	if param := c.QueryParam("coleccion"); param != "" {
+		coleccionID, err := primitive.ObjectIDFromHex(param)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de colección inválido"})
+		}
+		filtro["colecciones"] = coleccionID
+	}
+	if cursor != nil {
+		filtro = bson.M{"$and": []bson.M{filtro, utils.FiltroDespuesDe("fecha", cursor)}}
+	}
+	visibles, err := utils.FiltroListado(usuarioID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar la privacidad"})
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filtro}},
+		{{Key: "$sort", Value: bson.D{{Key: "fecha", Value: -1}, {Key: "_id", Value: -1}}}},
+		// Los guardados de posts en la papelera o purgados quedan sin post y se descartan
+		{{Key: "$lookup", Value: bson.M{"from": "posts", "localField": "postId", "foreignField": "_id", "as": "post"}}},
+		{{Key: "$unwind", Value: "$post"}},
+		{{Key: "$project", Value: bson.M{"post.adjuntos.texto": 0}}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": bson.M{"$mergeObjects": bson.A{
+			"$post",
+			bson.M{"guardadoId": "$_id", "guardadoEn": "$fecha", "colecciones": "$colecciones"},
+		}}}}},
+		// Los que ya no puede ver (p. ej. si el autor cambió la visibilidad) tampoco
+		{{Key: "$match", Value: visibles}},
+		// Se pide uno extra para saber si existe una página siguiente
+		{{Key: "$limit", Value: limite + 1}},
+	}
+	cur, err := config.GetCollection("guardados").Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar los guardados"})
+	}
+	defer cur.Close(context.TODO())
+
+	posts := []models.PostGuardado{}
+	if err := cur.All(context.TODO(), &posts); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer los guardados"})
+	}
+
+	var siguiente string
+	if len(posts) > limite {
+		posts = posts[:limite]
+		ultimo := posts[limite-1]
+		siguiente = utils.CodificarCursor(ultimo.GuardadoEn, ultimo.GuardadoID)
+	}
+	enPagina := make([]*models.Post, len(posts))
+	for i := range posts {
+		enPagina[i] = &posts[i].Post
+	}
+	if err := completarPosts(usuarioID, enPagina...); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al completar los guardados"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": posts, "nextCursor": siguiente})
+}
+
+// VerColecciones lista las colecciones de :id con cuántos posts tiene cada una.
+func VerColecciones(c echo.Context) error {
+	usuarioID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID inválido"})
+	}
+	ctx := context.TODO()
+
+	cur, err := config.GetCollection("colecciones_guardados").Find(ctx,
+		bson.M{"usuarioId": usuarioID},
+		options.Find().SetSort(bson.D{{Key: "nombre", Value: 1}}),
+	)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar las colecciones"})
+	}
+	colecciones := []models.ColeccionGuardados{}
+	if err := cur.All(ctx, &colecciones); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer las colecciones"})
+	}
+
+	cur, err = config.GetCollection("guardados").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"usuarioId": usuarioID}}},
+		{{Key: "$unwind", Value: "$colecciones"}},
+		{{Key: "$group", Value: bson.M{"_id": "$colecciones", "total": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al contar los guardados"})
+	}
+	var totales []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Total int64              `bson:"total"`
+	}
+	if err := cur.All(ctx, &totales); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al contar los guardados"})
+	}
+	porColeccion := make(map[primitive.ObjectID]int64, len(totales))
+	for _, t := range totales {
+		porColeccion[t.ID] = t.Total
+	}
+	for i := range colecciones {
+		colecciones[i].Total = porColeccion[colecciones[i].ID]
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": colecciones})
+}
+
+// CrearColeccion crea la colección {nombre} de :id.
+func CrearColeccion(c echo.Context) error {
+	usuarioID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID inválido"})
+	}
+	nombre, ok := nombreColeccion(c)
+	if !ok {
+		return nil
+	}
+
+	collection := config.GetCollection("colecciones_guardados")
+	total, err := collection.CountDocuments(context.TODO(), bson.M{"usuarioId": usuarioID})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al contar las colecciones"})
+	}
+	if total >= maxColeccionesGuardados {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Alcanzaste el máximo de colecciones"})
+	}
+
+	coleccion := models.ColeccionGuardados{
+		ID:        primitive.NewObjectID(),
+		UsuarioID: usuarioID,
+		Nombre:    nombre,
+		Fecha:     time.Now(),
+	}
+	_, err = collection.InsertOne(context.TODO(), coleccion)
+	if mongo.IsDuplicateKeyError(err) {
+		return c.JSON(http.StatusConflict, echo.Map{"message": "Ya tienes una colección con ese nombre"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al crear la colección"})
+	}
+	return c.JSON(http.StatusCreated, coleccion)
+}
+
+// RenombrarColeccion cambia el nombre de la colección :coleccionId de :id.
+func RenombrarColeccion(c echo.Context) error {
+	usuarioID, coleccionID, ok := idsColeccion(c)
+	if !ok {
+		return nil
+	}
+	nombre, ok := nombreColeccion(c)
+	if !ok {
+		return nil
+	}
+
+	var coleccion models.ColeccionGuardados
+	err := config.GetCollection("colecciones_guardados").FindOneAndUpdate(context.TODO(),
+		bson.M{"_id": coleccionID, "usuarioId": usuarioID},
+		bson.M{"$set": bson.M{"nombre": nombre}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&coleccion)
+	switch {
+	case err == mongo.ErrNoDocuments:
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "Colección no encontrada"})
+	case mongo.IsDuplicateKeyError(err):
+		return c.JSON(http.StatusConflict, echo.Map{"message": "Ya tienes una colección con ese nombre"})
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al renombrar la colección"})
+	}
+	return c.JSON(http.StatusOK, coleccion)
+}
+
+// EliminarColeccion borra la colección :coleccionId de :id. Sus posts siguen
+// guardados.
+func EliminarColeccion(c echo.Context) error {
+	usuarioID, coleccionID, ok := idsColeccion(c)
+	if !ok {
+		return nil
+	}
+
+	res, err := config.GetCollection("colecciones_guardados").DeleteOne(context.TODO(), bson.M{"_id": coleccionID, "usuarioId": usuarioID})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al eliminar la colección"})
+	}
+	if res.DeletedCount == 0 {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "Colección no encontrada"})
+	}
+	_, err = config.GetCollection("guardados").UpdateMany(context.TODO(),
+		bson.M{"usuarioId": usuarioID, "colecciones": coleccionID},
+		bson.M{"$pull": bson.M{"colecciones": coleccionID}},
+	)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al actualizar los guardados"})
+	}
+	return c.JSON(http.StatusOK, echo.Map{"message": "Colección eliminada"})
+}
+
+// AgregarAColeccion guarda el post :postId (si no lo estaba) en la colección
+// :coleccionId de :id.
+func AgregarAColeccion(c echo.Context) error {
+	usuarioID, coleccionID, ok := idsColeccion(c)
+	if !ok {
+		return nil
+	}
+	colecciones, ok := coleccionesDelUsuario(c, []string{coleccionID.Hex()}, usuarioID)
+	if !ok {
+		return nil
+	}
+	postID, ok := postParaGuardar(c, c.Param("postId"), usuarioID)
+	if !ok {
+		return nil
+	}
+
+	if err := utils.GuardarPost(usuarioID, postID, colecciones); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al guardar el post"})
+	}
+	return c.JSON(http.StatusOK, echo.Map{"saved": true})
+}
+
+// QuitarDeColeccion saca el post :postId de la colección :coleccionId de :id; el
+// post sigue guardado.
+func QuitarDeColeccion(c echo.Context) error {
+	usuarioID, coleccionID, ok := idsColeccion(c)
+	if !ok {
+		return nil
+	}
+	postID, err := primitive.ObjectIDFromHex(c.Param("postId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID del post inválido"})
+	}
+
+	_, err = config.GetCollection("guardados").UpdateOne(context.TODO(),
+		bson.M{"usuarioId": usuarioID, "postId": postID},
+		bson.M{"$pull": bson.M{"colecciones": coleccionID}},
+	)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al quitar el post de la colección"})
+	}
+	return c.JSON(http.StatusOK, echo.Map{"message": "Post quitado de la colección"})
+}
+
+// marcarGuardados completa Post.Guardado para viewerID en los posts recibidos.
+func marcarGuardados(viewerID primitive.ObjectID, posts ...*models.Post) error {
+	ids := make([]primitive.ObjectID, 0, len(posts))
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+	}
+	guardados, err := utils.PostsGuardados(viewerID, ids)
+	if err != nil {
+		return err
+	}
+	for _, p := range posts {
+		p.Guardado = guardados[p.ID]
+	}
+	return nil
+}
+
+// postParaGuardar verifica que el post exista y que usuarioID pueda verlo. Si no,
+// responde al cliente y devuelve ok = false.
+func postParaGuardar(c echo.Context, idParam string, usuarioID primitive.ObjectID) (primitive.ObjectID, bool) {
+	postID, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, echo.Map{"message": "ID del post inválido"})
+		return primitive.NilObjectID, false
+	}
+	// Guardar un repost guarda el post compartido
+	var post models.Post
+	err = config.GetCollection("posts").FindOne(context.TODO(), bson.M{"_id": postID},
+		options.FindOne().SetProjection(bson.M{"repostDe": 1})).Decode(&post)
+	if err != nil && err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar el post"})
+		return primitive.NilObjectID, false
+	}
+	if post.RepostDe != nil {
+		postID = *post.RepostDe
+	}
+	if !postVisible(c, usuarioID, postID) {
+		return primitive.NilObjectID, false
+	}
+	return postID, true
+}
+
+// coleccionesDelUsuario convierte los ids recibidos verificando que sean
+// colecciones de usuarioID. Si alguno no lo es responde al cliente y devuelve ok = false.
+func coleccionesDelUsuario(c echo.Context, ids []string, usuarioID primitive.ObjectID) ([]primitive.ObjectID, bool) {
+	if len(ids) == 0 {
+		return nil, true
+	}
+	oids := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de colección inválido"})
+			return nil, false
+		}
+		oids = append(oids, oid)
+	}
+	total, err := config.GetCollection("colecciones_guardados").CountDocuments(context.TODO(),
+		bson.M{"_id": bson.M{"$in": oids}, "usuarioId": usuarioID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar las colecciones"})
+		return nil, false
+	}
+	if int(total) != len(oids) {
+		c.JSON(http.StatusNotFound, echo.Map{"message": "Colección no encontrada"})
+		return nil, false
+	}
+	return oids, true
+}
+
+// idsColeccion lee :id y :coleccionId. Si alguno es inválido responde al cliente y
+// devuelve ok = false.
+func idsColeccion(c echo.Context) (primitive.ObjectID, primitive.ObjectID, bool) {
+	usuarioID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, echo.Map{"message": "ID inválido"})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+	coleccionID, err := primitive.ObjectIDFromHex(c.Param("coleccionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de colección inválido"})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+	return usuarioID, coleccionID, true
+}
+
+// nombreColeccion lee y valida {nombre}. Si no es válido responde al cliente y
+// devuelve ok = false.
+func nombreColeccion(c echo.Context) (string, bool) {
+	var body struct {
+		Nombre string `json:"nombre"`
+	}
+	if err := c.Bind(&body); err != nil {
+		c.JSON(http.StatusBadRequest, echo.Map{"message": "Error al parsear el cuerpo JSON"})
+		return "", false
+	}
+	nombre := strings.TrimSpace(body.Nombre)
+	if nombre == "" || utf8.RuneCountInString(nombre) > maxNombreColeccion {
+		c.JSON(http.StatusBadRequest, echo.Map{"message": "El nombre debe tener entre 1 y 50 caracteres"})
+		return "", false
+	}
+	return nombre, true
+}
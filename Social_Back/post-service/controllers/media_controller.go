@@ -0,0 +1,321 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"mime"
+	"net/http"
+	"post-service/config"
+	"post-service/models"
+	"post-service/storage"
+	"post-service/utils"
+	"regexp"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	maxAdjuntosPorPost    = 10               // archivos que se pueden adjuntar a un post
+	expiraSubidaDirecta   = 30 * time.Minute // validez de las URLs prefirmadas de subida
+	expiraDescargaDirecta = time.Hour        // validez de las URLs prefirmadas de descarga
+)
+
+// patronArchivoMedia valida los nombres pedidos en GET /media/:archivo: el original
+// (<hash><ext>) o un archivo generado al procesarlo (<hash>_<sufijo><ext>: variantes
+// de imagen, póster, MP4 web, playlists y segmentos HLS).
+var patronArchivoMedia = regexp.MustCompile(`^([0-9a-f]{64})(_[a-z0-9_]+)?(\.[a-z0-9]+)$`)
+
+// mimeVariantes es el tipo de los archivos generados según su extensión.
+var mimeVariantes = map[string]string{
+	".jpg":  "image/jpeg",
+	".webp": "image/webp",
+	".mp4":  "video/mp4",
+	".m3u8": "application/vnd.apple.mpegurl",
+	".ts":   "video/mp2t",
+}
+
+// SubirMedia recibe un archivo multipart (campo "archivo") de usuarioId y guarda
+// sus metadatos en "adjuntos". El id devuelto se envía luego en "adjuntos" al
+// crear el post.
+func SubirMedia(c echo.Context) error {
+	// Sin límite, un cliente podría llenar el disco con los temporales del multipart
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, utils.MaxCuerpoSubida)
+	if err := c.Request().ParseMultipartForm(32 << 20); err != nil {
+		var muyGrande *http.MaxBytesError
+		if errors.As(err, &muyGrande) {
+			return c.JSON(http.StatusRequestEntityTooLarge, echo.Map{"message": "El archivo supera el tamaño permitido"})
+		}
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Formulario inválido"})
+	}
+
+	usuarioID, err := primitive.ObjectIDFromHex(c.FormValue("usuarioId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "usuarioId inválido"})
+	}
+
+	adjunto, err := utils.SubirArchivo(c, "archivo")
+	switch {
+	case errors.Is(err, utils.ErrTipoNoPermitido):
+		return c.JSON(http.StatusUnsupportedMediaType, echo.Map{"message": "Tipo de archivo no permitido"})
+	case errors.Is(err, utils.ErrArchivoMuyGrande):
+		return c.JSON(http.StatusRequestEntityTooLarge, echo.Map{"message": "El archivo supera el tamaño permitido"})
+	case errors.Is(err, http.ErrMissingFile):
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Falta el archivo"})
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al guardar el archivo"})
+	}
+
+	adjunto.ID = primitive.NewObjectID()
+	adjunto.SubidoPor = usuarioID
+	if utils.RequiereProcesamiento(adjunto.Tipo) {
+		adjunto.Procesamiento = models.ProcesamientoEnCurso
+	}
+	if _, err := config.GetCollection("adjuntos").InsertOne(context.TODO(), adjunto); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al registrar el archivo"})
+	}
+	utils.EncolarProcesamiento(adjunto)
+
+	return c.JSON(http.StatusCreated, adjunto)
+}
+
+// PresignarSubida reserva un adjunto y devuelve una URL prefirmada para que el
+// cliente suba el archivo directamente al almacenamiento (útil para videos
+// grandes). Recibe {usuarioId, nombre, mime, tamano, sha256} y devuelve la URL y
+// los campos del formulario POST: el almacenamiento rechaza un archivo de otro
+// tamaño, tipo o contenido. Tras subirlo se llama a POST /media/:id/confirm.
+func PresignarSubida(c echo.Context) error {
+	var body struct {
+		UsuarioID string `json:"usuarioId"`
+		Nombre    string `json:"nombre"`
+		MIME      string `json:"mime"`
+		Tamano    int64  `json:"tamano"`
+		Sha256    string `json:"sha256"` // en hexadecimal
+	}
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Datos inválidos"})
+	}
+	usuarioID, err := primitive.ObjectIDFromHex(body.UsuarioID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "usuarioId inválido"})
+	}
+	suma, err := hex.DecodeString(body.Sha256)
+	if err != nil || len(suma) != sha256.Size {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "sha256 inválido"})
+	}
+	switch err := utils.ValidarSubidaDirecta(body.MIME, body.Tamano); {
+	case errors.Is(err, utils.ErrTipoNoPermitido):
+		return c.JSON(http.StatusUnsupportedMediaType, echo.Map{"message": "Tipo de archivo no permitido"})
+	case errors.Is(err, utils.ErrArchivoMuyGrande):
+		return c.JSON(http.StatusRequestEntityTooLarge, echo.Map{"message": "El archivo supera el tamaño permitido"})
+	}
+
+	adjunto := models.Adjunto{
+		ID:        primitive.NewObjectID(),
+		Nombre:    body.Nombre,
+		MIME:      body.MIME,
+		SubidoPor: usuarioID,
+		Estado:    "pendiente",
+		Fecha:     time.Now(),
+	}
+	subida := storage.SubidaDirecta{MIME: body.MIME, Tamano: body.Tamano, Sha256: suma}
+	url, campos, err := storage.Actual.PresignPost(context.TODO(), utils.ClavePendiente(adjunto.ID.Hex()), subida, expiraSubidaDirecta)
+	if errors.Is(err, storage.ErrNoSoportado) {
+		return c.JSON(http.StatusNotImplemented, echo.Map{"message": "El almacenamiento no admite subidas directas; usa POST /media"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al generar la URL de subida"})
+	}
+	if _, err := config.GetCollection("adjuntos").InsertOne(context.TODO(), adjunto); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al registrar el archivo"})
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"id":     adjunto.ID,
+		"url":    url,
+		"metodo": http.MethodPost,
+		"campos": campos, // van antes del campo "file" en el multipart
+		"expira": time.Now().Add(expiraSubidaDirecta),
+	})
+}
+
+// ConfirmarSubida valida el archivo subido con PresignarSubida y completa sus metadatos.
+func ConfirmarSubida(c echo.Context) error {
+	adjuntoID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de archivo inválido"})
+	}
+	var body struct {
+		UsuarioID string `json:"usuarioId"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Datos inválidos"})
+	}
+	usuarioID, err := primitive.ObjectIDFromHex(body.UsuarioID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "usuarioId inválido"})
+	}
+
+	collection := config.GetCollection("adjuntos")
+	filtro := bson.M{"_id": adjuntoID, "subidoPor": usuarioID, "estado": "pendiente"}
+	var pendiente models.Adjunto
+	err = collection.FindOne(context.TODO(), filtro).Decode(&pendiente)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "Subida pendiente no encontrada"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar el archivo"})
+	}
+
+	adjunto, err := utils.ConfirmarSubidaDirecta(adjuntoID.Hex(), pendiente.Nombre)
+	switch {
+	case errors.Is(err, storage.ErrNoExiste):
+		return c.JSON(http.StatusConflict, echo.Map{"message": "El archivo todavía no se subió"})
+	case errors.Is(err, utils.ErrTipoNoPermitido), errors.Is(err, utils.ErrArchivoMuyGrande):
+		collection.DeleteOne(context.TODO(), bson.M{"_id": adjuntoID})
+		if errors.Is(err, utils.ErrTipoNoPermitido) {
+			return c.JSON(http.StatusUnsupportedMediaType, echo.Map{"message": "Tipo de archivo no permitido"})
+		}
+		return c.JSON(http.StatusRequestEntityTooLarge, echo.Map{"message": "El archivo supera el tamaño permitido"})
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar el archivo"})
+	}
+
+	adjunto.ID = adjuntoID
+	adjunto.SubidoPor = usuarioID
+	if utils.RequiereProcesamiento(adjunto.Tipo) {
+		adjunto.Procesamiento = models.ProcesamientoEnCurso
+	}
+	if _, err := collection.ReplaceOne(context.TODO(), filtro, adjunto); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al registrar el archivo"})
+	}
+	utils.EncolarProcesamiento(adjunto)
+
+	return c.JSON(http.StatusOK, adjunto)
+}
+
+// ServirMedia entrega un archivo subido (o una de sus variantes) con su tipo MIME
+// detectado y caché permanente (el nombre depende del contenido). Si el
+// almacenamiento admite URLs prefirmadas redirige a él; si no, lo transmite con
+// soporte de Range para video.
+func ServirMedia(c echo.Context) error {
+	archivo := c.Param("archivo")
+	partes := patronArchivoMedia.FindStringSubmatch(archivo)
+	if partes == nil {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "Archivo no encontrado"})
+	}
+	esVariante := partes[2] != ""
+	filtro := bson.M{"archivo": archivo}
+	if esVariante {
+		filtro = bson.M{"hash": partes[1], "procesamiento": models.ProcesamientoListo}
+	}
+
+	var adjunto models.Adjunto
+	err := config.GetCollection("adjuntos").FindOne(context.TODO(), filtro).Decode(&adjunto)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "Archivo no encontrado"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar el archivo"})
+	}
+
+	disposicion := "inline"
+	if adjunto.Tipo == "documento" {
+		disposicion = "attachment"
+	}
+	if adjunto.Nombre != "" && !esVariante {
+		disposicion = mime.FormatMediaType(disposicion, map[string]string{"filename": adjunto.Nombre})
+	}
+	tipoMIME, etag := adjunto.MIME, adjunto.Hash
+	if esVariante {
+		tipoMIME, etag = mimeVariantes[partes[3]], adjunto.Hash+partes[2]
+		if tipoMIME == "" {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "Archivo no encontrado"})
+		}
+	}
+
+	ctx := context.TODO()
+	clave := utils.ClaveArchivo(archivo)
+	url, err := storage.Actual.PresignGet(ctx, clave, expiraDescargaDirecta, map[string]string{
+		echo.HeaderContentType:        tipoMIME,
+		echo.HeaderContentDisposition: disposicion,
+	})
+	if err == nil {
+		return c.Redirect(http.StatusFound, url)
+	}
+	if !errors.Is(err, storage.ErrNoSoportado) {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al generar la URL de descarga"})
+	}
+
+	obj, err := storage.Actual.Get(ctx, clave)
+	if errors.Is(err, storage.ErrNoExiste) {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "Archivo no encontrado"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer el archivo"})
+	}
+	defer obj.Close()
+
+	h := c.Response().Header()
+	h.Set(echo.HeaderContentType, tipoMIME)
+	h.Set(echo.HeaderXContentTypeOptions, "nosniff")
+	h.Set(echo.HeaderContentSecurityPolicy, "default-src 'none'; sandbox")
+	h.Set("Cache-Control", "public, max-age=31536000, immutable")
+	h.Set("ETag", `"`+etag+`"`)
+	h.Set(echo.HeaderContentDisposition, disposicion)
+
+	// ServeContent resuelve Range, If-Range y If-None-Match
+	http.ServeContent(c.Response(), c.Request(), "", obj.Modificado(), obj)
+	return nil
+}
+
+// adjuntosDelUsuario busca los adjuntos indicados verificando que los haya subido
+// usuarioID. Conserva el orden recibido.
+func adjuntosDelUsuario(ids []string, usuarioID primitive.ObjectID) ([]models.Adjunto, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if len(ids) > maxAdjuntosPorPost {
+		return nil, errValidacion("Demasiados adjuntos")
+	}
+	oids := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, errValidacion("Adjunto inválido")
+		}
+		oids = append(oids, oid)
+	}
+
+	cur, err := config.GetCollection("adjuntos").Find(context.TODO(), bson.M{
+		"_id":       bson.M{"$in": oids},
+		"subidoPor": usuarioID,
+		"estado":    bson.M{"$ne": "pendiente"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var encontrados []models.Adjunto
+	if err := cur.All(context.TODO(), &encontrados); err != nil {
+		return nil, err
+	}
+	porID := make(map[primitive.ObjectID]models.Adjunto, len(encontrados))
+	for _, a := range encontrados {
+		porID[a.ID] = a
+	}
+
+	adjuntos := make([]models.Adjunto, 0, len(oids))
+	for _, oid := range oids {
+		a, ok := porID[oid]
+		if !ok {
+			return nil, errValidacion("Adjunto no encontrado")
+		}
+		adjuntos = append(adjuntos, a)
+	}
+	return adjuntos, nil
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"post-service/config"
+	"post-service/models"
+	"post-service/utils"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// candidatosAutocompletar son los usuarios leídos por prefijo antes de priorizar a los seguidos.
+const candidatosAutocompletar = 50
+
+// AutocompletarMenciones sugiere usuarios cuyo username empieza por ?q= para que
+// :id los mencione. Primero aparecen los usuarios que :id sigue y nunca los que
+// tienen un bloqueo con él.
+func AutocompletarMenciones(c echo.Context) error {
+	usuarioID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de usuario inválido"})
+	}
+	prefijo := strings.TrimLeft(strings.TrimSpace(c.QueryParam("q")), "@")
+	if prefijo == "" {
+		return c.JSON(http.StatusOK, echo.Map{"data": []models.UsuarioPublico{}})
+	}
+	limite := utils.ParsearLimite(c.QueryParam("limit"), 8, 20)
+
+	excluidos, err := utils.UsuariosBloqueados(usuarioID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar bloqueos"})
+	}
+	excluidos = append(excluidos, usuarioID)
+
+	ctx := context.TODO()
+	opts := options.Find().
+		SetProjection(proyeccionUsuarioPublico).
+		SetSort(bson.M{"username": 1}).
+		SetLimit(candidatosAutocompletar)
+	cur, err := config.GetCollection("users").Find(ctx, bson.M{
+		"username": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(prefijo), Options: "i"},
+		"_id":      bson.M{"$nin": excluidos},
+	}, opts)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar usuarios"})
+	}
+	var usuarios []models.UsuarioPublico
+	if err := cur.All(ctx, &usuarios); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer usuarios"})
+	}
+	if len(usuarios) == 0 {
+		return c.JSON(http.StatusOK, echo.Map{"data": []models.UsuarioPublico{}})
+	}
+
+	// Priorizar a los usuarios que ya sigue
+	ids := make([]primitive.ObjectID, 0, len(usuarios))
+	for _, u := range usuarios {
+		ids = append(ids, u.ID)
+	}
+	valores, err := config.GetCollection("follows").Distinct(ctx, "seguidoId", bson.M{
+		"seguidorId": usuarioID,
+		"seguidoId":  bson.M{"$in": ids},
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer seguidos"})
+	}
+	seguidos := map[primitive.ObjectID]bool{}
+	for _, id := range utils.IDsDeDistinct(valores) {
+		seguidos[id] = true
+	}
+	sort.SliceStable(usuarios, func(i, j int) bool {
+		return seguidos[usuarios[i].ID] && !seguidos[usuarios[j].ID]
+	})
+	if len(usuarios) > limite {
+		usuarios = usuarios[:limite]
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": usuarios})
+}
@@ -2,16 +2,20 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"post-service/config"
 	"post-service/models"
 	"post-service/utils"
+	"slices"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // CrearPost maneja la creación de un nuevo post a partir de datos recibidos como JSON.
@@ -24,6 +28,15 @@ func CrearPost(c echo.Context) error {
 		Categoria string   `json:"categoria"`
 		Tags      []string `json:"tags"`
 		AutorID   string   `json:"autorId"`
+		Adjuntos  []string `json:"adjuntos"` // ids devueltos por POST /media
+		CitaDe    string   `json:"citaDe"`   // post citado (opcional)
+		// Quién puede verlo: public (por defecto), followers, institution, group
+		// (con grupoId) o private
+		Visibilidad string `json:"visibilidad"`
+		GrupoID     string `json:"grupoId"`
+		// draft, scheduled (con publishAt) o published (por defecto)
+		Estado     string     `json:"status"`
+		PublicarEn *time.Time `json:"publishAt"`
 	}
 
 	// Intentar parsear el cuerpo como JSON
@@ -37,6 +50,39 @@ func CrearPost(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de autor inválido"})
 	}
 
+	audiencia, ok := audienciaPedida(c, autorID, body.Visibilidad, body.GrupoID)
+	if !ok {
+		return nil
+	}
+	estado, publicarEn, ok := estadoPedido(c, body.Estado, body.PublicarEn)
+	if !ok {
+		return nil
+	}
+
+	var citado *models.Post
+	if body.CitaDe != "" {
+		original, ok := postACompartir(c, body.CitaDe, autorID)
+		if !ok {
+			return nil
+		}
+		citado = &original
+	}
+
+	adjuntos, err := adjuntosDelUsuario(body.Adjuntos, autorID)
+	var invalido errValidacion
+	if errors.As(err, &invalido) {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": invalido.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar los adjuntos"})
+	}
+
+	// Resolver los @username del contenido
+	menciones, err := utils.ResolverMenciones(body.Contenido, autorID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al resolver menciones"})
+	}
+
 	// Crear el objeto post con los datos recibidos
 	post := models.Post{
 		ID:          primitive.NewObjectID(),
@@ -44,9 +90,30 @@ func CrearPost(c echo.Context) error {
 		Contenido:   body.Contenido,
 		Tipo:        body.Tipo,
 		Categoria:   body.Categoria,
-		Tags:        body.Tags,
+		Tags:        utils.UnirTags(body.Tags, utils.ExtraerHashtags(body.Contenido)),
+		Menciones:   menciones,
+		Adjuntos:    adjuntos,
 		AutorID:     autorID,
 		FechaCreado: time.Now(),
+		Idioma:      utils.DetectarIdioma(body.Titulo + " " + body.Contenido),
+	}
+
+	if len(adjuntos) > 0 {
+		post.URLArchivo = adjuntos[0].URL
+		post.Procesamiento = utils.EstadoProcesamiento(adjuntos)
+	}
+	if citado != nil {
+		post.CitaDe = &citado.ID
+	}
+	audiencia.aplicar(&post)
+	post.Estado = estado
+	if estado == models.EstadoProgramado {
+		// Se ordena por el momento en que aparece, no por cuándo se escribió
+		post.PublicarEn = publicarEn
+		post.FechaCreado = *publicarEn
+	}
+	if estado == models.EstadoPublicado {
+		post.Difundiendo = &post.FechaCreado
 	}
 
 	// Guardar el post en MongoDB
@@ -56,10 +123,19 @@ func CrearPost(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al guardar el post"})
 	}
 
+	if post.Procesamiento == models.ProcesamientoEnCurso {
+		utils.SincronizarAdjuntos(post)
+	}
+	// Tags, menciones, timelines y notificaciones esperan a que se publique
+	if estado == models.EstadoPublicado {
+		go utils.Difundir(post)
+	}
+	post.Original = citado
+
 	return c.JSON(http.StatusCreated, post)
 }
 
-// ObtenerPosts devuelve todos los posts (con filtros)
+// ObtenerPosts devuelve los posts paginados (con filtros y orden)
 func ObtenerPosts(c echo.Context) error {
 	tipo := c.QueryParam("tipo")
 	categoria := c.QueryParam("categoria")
@@ -73,25 +149,25 @@ func ObtenerPosts(c echo.Context) error {
 		filtro["categoria"] = categoria
 	}
 	if tag != "" {
-		filtro["tags"] = tag
+		filtro["tags"] = utils.NormalizarTag(tag)
 	}
+	// Los reposts solo aparecen en el perfil y en el feed de quien los hizo
+	filtro["repostDe"] = nil
 
-	collection := config.GetCollection("posts")
-	cursor, err := collection.Find(context.TODO(), filtro)
+	// Solo los posts que el viewer puede ver, sin los de usuarios que silenció
+	viewerID, err := viewerDesdeQuery(c)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar los posts"})
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "viewerId inválido"})
 	}
-	defer cursor.Close(context.TODO())
-
-	var posts []models.Post
-	if err := cursor.All(context.TODO(), &posts); err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer los posts"})
+	visibles, err := utils.FiltroListado(viewerID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar la privacidad"})
 	}
 
-	return c.JSON(http.StatusOK, posts)
+	return paginarPosts(c, bson.M{"$and": []bson.M{filtro, visibles}})
 }
 
-// ObtenerPostsPorUsuario devuelve todos los posts creados por un usuario específico
+// ObtenerPostsPorUsuario devuelve, paginados, los posts creados por un usuario específico
 func ObtenerPostsPorUsuario(c echo.Context) error {
 	usuarioIDParam := c.Param("id")
 	usuarioID, err := primitive.ObjectIDFromHex(usuarioIDParam)
@@ -99,105 +175,451 @@ func ObtenerPostsPorUsuario(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de usuario inválido"})
 	}
 
-	collection := config.GetCollection("posts")
-	cursor, err := collection.Find(context.TODO(), bson.M{"autorId": usuarioID})
+	viewerID, err := viewerDesdeQuery(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "viewerId inválido"})
+	}
+	bloqueado, err := utils.HayBloqueo(viewerID, usuarioID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar bloqueos"})
+	}
+	if bloqueado {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "Usuario no encontrado"})
+	}
+	puedeVer, err := utils.PuedeVerPostsDe(viewerID, usuarioID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar la privacidad"})
+	}
+	if !puedeVer {
+		return c.JSON(http.StatusForbidden, echo.Map{"message": "Esta cuenta es privada"})
+	}
+	visibles, err := utils.FiltroVisibles(viewerID)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar posts del usuario"})
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar la privacidad"})
 	}
-	defer cursor.Close(context.TODO())
 
-	var posts []models.Post
-	if err := cursor.All(context.TODO(), &posts); err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer posts"})
+	// Los borradores y programados del autor se ven en GET /users/:id/drafts
+	return paginarPosts(c, bson.M{"$and": []bson.M{{"autorId": usuarioID}, visibles, utils.FiltroPublicados()}})
+}
+
+// ordenesPosts asocia cada valor aceptado en ?orden= con el campo por el que se ordena.
+var ordenesPosts = map[string]string{
+	"recientes":   "fechaCreado",
+	"likes":       "totalLikes",
+	"comentarios": "totalComentarios",
+}
+
+// paginarPosts responde una página de posts que cumplen filtro usando ?orden=,
+// ?limit= y el cursor opaco ?cursor= devuelto en la página anterior (nextCursor).
+func paginarPosts(c echo.Context, filtro bson.M) error {
+	orden := c.QueryParam("orden")
+	if orden == "" {
+		orden = "recientes"
+	}
+	campo, ok := ordenesPosts[orden]
+	if !ok {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Orden no válido (recientes, likes, comentarios)"})
+	}
+
+	// Los cursores por fecha no llevan Orden; los de un contador, el suyo
+	ordenCursor := orden
+	if orden == "recientes" {
+		ordenCursor = ""
+	}
+	cursor, err := utils.DecodificarCursor(c.QueryParam("cursor"))
+	if err != nil || (cursor != nil && cursor.Orden != ordenCursor) {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Cursor inválido"})
 	}
+	limite := utils.ParsearLimite(c.QueryParam("limit"), 20, 50)
 
-	return c.JSON(http.StatusOK, posts)
+	if cursor != nil {
+		var despues bson.M
+		if orden == "recientes" {
+			despues = utils.FiltroDespuesDe(campo, cursor)
+		} else {
+			despues = utils.FiltroDespuesDeConteo(campo, cursor)
+		}
+		filtro = bson.M{"$and": []bson.M{filtro, despues}}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: campo, Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limite + 1)).
+		SetProjection(utils.SinTextoAdjuntos)
+	cur, err := config.GetCollection("posts").Find(context.TODO(), filtro, opts)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar los posts"})
+	}
+	defer cur.Close(context.TODO())
+
+	posts := []models.Post{}
+	if err := cur.All(context.TODO(), &posts); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer los posts"})
+	}
+
+	var siguiente string
+	if len(posts) > limite {
+		posts = posts[:limite]
+		ultimo := posts[limite-1]
+		switch orden {
+		case "likes":
+			siguiente = utils.CodificarCursorConteo(orden, ultimo.TotalLikes, ultimo.ID)
+		case "comentarios":
+			siguiente = utils.CodificarCursorConteo(orden, ultimo.TotalComentarios, ultimo.ID)
+		default:
+			siguiente = utils.CodificarCursor(ultimo.FechaCreado, ultimo.ID)
+		}
+	}
+
+	viewerID, _ := viewerDesdeQuery(c)
+	if err := completarPosts(viewerID, punteros(posts)...); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al completar los posts"})
+	}
+	posts = sinRepostsOcultos(posts)
+
+	return c.JSON(http.StatusOK, echo.Map{"data": posts, "nextCursor": siguiente})
+}
+
+// punteros devuelve punteros a cada post para completarlos en el lugar.
+func punteros(posts []models.Post) []*models.Post {
+	ps := make([]*models.Post, len(posts))
+	for i := range posts {
+		ps[i] = &posts[i]
+	}
+	return ps
 }
 
-// EliminarPost elimina un post por su ID
+// EliminarPost manda un post a la papelera, de donde se puede restaurar durante
+// PAPELERA_DIAS. Con ?definitivo=true (o PAPELERA_DIAS=0) lo purga en el momento
+// junto con sus comentarios, reacciones, notificaciones y archivos. Solo su autor
+// o un moderador ({usuarioId}) pueden hacerlo.
 func EliminarPost(c echo.Context) error {
 	idParam := c.Param("id")
 	id, err := primitive.ObjectIDFromHex(idParam)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID no válido"})
 	}
+	var body struct {
+		UsuarioID string `json:"usuarioId"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Error al parsear el cuerpo JSON"})
+	}
+	usuarioID, err := primitive.ObjectIDFromHex(body.UsuarioID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de usuario inválido"})
+	}
 
-	collection := config.GetCollection("posts")
-	_, err = collection.DeleteOne(context.TODO(), bson.M{"_id": id})
+	var post models.Post
+	err = config.GetCollection("posts").FindOne(context.TODO(), bson.M{"_id": id}).Decode(&post)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "Post no encontrado"})
+	}
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al eliminar"})
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar el post"})
+	}
+	if post.AutorID != usuarioID {
+		moderador, err := utils.EsModerador(usuarioID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar permisos"})
+		}
+		if !moderador {
+			return c.JSON(http.StatusForbidden, echo.Map{"message": "Solo el autor puede eliminar el post"})
+		}
+	}
+
+	// Un repost no tiene contenido que recuperar: se purga siempre
+	if c.QueryParam("definitivo") == "true" || utils.DiasPapelera() == 0 || post.RepostDe != nil {
+		if err := utils.PurgarPost(post); err != nil {
+			// Lo que falte lo retoma la limpieza periódica
+			log.Println("Error purgando el post", id.Hex(), ":", err)
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al eliminar"})
+		}
+		return c.JSON(http.StatusOK, echo.Map{"message": "Post eliminado"})
 	}
 
-	return c.JSON(http.StatusOK, echo.Map{"message": "Post eliminado"})
+	if err := utils.MoverAPapelera(post); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al eliminar"})
+	}
+	return c.JSON(http.StatusOK, echo.Map{
+		"message":          "Post eliminado",
+		"restaurableHasta": utils.PlazoRestauracion(time.Now()),
+	})
 }
 
-// ToggleLike registra o elimina un like sobre un post.
-func ToggleLike(c echo.Context) error {
-	// Obtener el ID del post desde la URL
-	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+// RestaurarPost devuelve a su lugar un post de la papelera. Solo su autor o un
+// moderador ({usuarioId}) pueden hacerlo, y solo dentro del plazo.
+func RestaurarPost(c echo.Context) error {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID del post inválido"})
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID no válido"})
 	}
-
-	// Estructura auxiliar para parsear el JSON con el usuario que da like
 	var body struct {
 		UsuarioID string `json:"usuarioId"`
 	}
-
-	// Parsear el cuerpo de la solicitud
 	if err := c.Bind(&body); err != nil {
 		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Error al parsear el cuerpo JSON"})
 	}
-
-	// Validar el ID del usuario
-	userID, err := primitive.ObjectIDFromHex(body.UsuarioID)
+	usuarioID, err := primitive.ObjectIDFromHex(body.UsuarioID)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de usuario inválido"})
 	}
 
-	// Verificar si ya existe el like
-	collection := config.GetCollection("likes")
-	filter := bson.M{"postId": postID, "usuarioId": userID}
+	var eliminado models.PostEliminado
+	err = config.GetCollection("posts_papelera").FindOne(context.TODO(), bson.M{"_id": id}).Decode(&eliminado)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "El post no está en la papelera"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar el post"})
+	}
+	if eliminado.AutorID != usuarioID {
+		moderador, err := utils.EsModerador(usuarioID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar permisos"})
+		}
+		if !moderador {
+			return c.JSON(http.StatusForbidden, echo.Map{"message": "Solo el autor puede restaurar el post"})
+		}
+	}
+	if time.Now().After(utils.PlazoRestauracion(eliminado.EliminadoEn)) {
+		return c.JSON(http.StatusGone, echo.Map{"message": "Venció el plazo para restaurar el post"})
+	}
 
-	var existing models.Like
-	err = collection.FindOne(context.TODO(), filter).Decode(&existing)
-	if err == nil {
-		// Ya existe → quitar el like
-		_, _ = collection.DeleteOne(context.TODO(), filter)
-		log.Println("Like quitado:", userID.Hex(), "en post", postID.Hex())
-		return c.JSON(http.StatusOK, echo.Map{"message": "Like quitado"})
+	if err := utils.RestaurarDePapelera(eliminado); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al restaurar el post"})
 	}
+	return c.JSON(http.StatusOK, eliminado.Post)
+}
 
-	// Crear nuevo like
-	like := models.Like{
-		ID:        primitive.NewObjectID(),
-		PostID:    postID,
-		UsuarioID: userID,
-		Fecha:     time.Now(),
+// ObtenerPostPorID devuelve un post con su autor, sus contadores de reacciones y
+// comentarios y la reacción de quien consulta (?viewerId=).
+func ObtenerPostPorID(c echo.Context) error {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID del post inválido"})
 	}
-	_, err = collection.InsertOne(context.TODO(), like)
+	viewerID, err := viewerDesdeQuery(c)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al dar like"})
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "viewerId inválido"})
 	}
 
-	// Obtener el post para identificar al autor
+	// Bloqueos, cuentas privadas y la visibilidad del post se tratan igual: como si
+	// el post no existiera
+	if !postVisible(c, viewerID, postID) {
+		return nil
+	}
 	var post models.Post
 	err = config.GetCollection("posts").FindOne(context.TODO(), bson.M{"_id": postID}).Decode(&post)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "Post no encontrado"})
+	}
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "No se pudo obtener el autor del post"})
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar el post"})
 	}
 
-	log.Println("Autor del post:", post.AutorID.Hex())
-	log.Println("Usuario que dio like:", userID.Hex())
+	detalle := models.PostDetalle{Post: post}
+	if err := completarPosts(viewerID, &detalle.Post); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al completar el post"})
+	}
+
+	var autor models.UsuarioPublico
+	err = config.GetCollection("users").FindOne(context.TODO(), bson.M{"_id": post.AutorID},
+		options.FindOne().SetProjection(proyeccionUsuarioPublico)).Decode(&autor)
+	if err == nil {
+		detalle.Autor = &autor
+	} else if err != mongo.ErrNoDocuments {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar el autor"})
+	}
+
+	if !viewerID.IsZero() {
+		filtro := utils.FiltroReaccion(postID, nil)
+		filtro["usuarioId"] = viewerID
+		var reaccion models.Reaccion
+		err := config.GetCollection("reacciones").FindOne(context.TODO(), filtro).Decode(&reaccion)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar la reacción"})
+		}
+		detalle.LeGusta = err == nil
+		detalle.Reaccion = reaccion.Tipo
+	}
+
+	return c.JSON(http.StatusOK, detalle)
+}
+
+// ActualizarPost permite al autor editar título, contenido, tags y categoría.
+// Si ya estaba publicado, la versión anterior se guarda en la colección
+// post_revisiones.
+func ActualizarPost(c echo.Context) error {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID del post inválido"})
+	}
+
+	// Los campos omitidos no se modifican
+	var body struct {
+		AutorID   string    `json:"autorId"`
+		Titulo    *string   `json:"titulo"`
+		Contenido *string   `json:"contenido"`
+		Categoria *string   `json:"categoria"`
+		Tags      *[]string `json:"tags"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Error al parsear el cuerpo JSON"})
+	}
+	autorID, err := primitive.ObjectIDFromHex(body.AutorID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de autor inválido"})
+	}
+
+	collection := config.GetCollection("posts")
+	var post models.Post
+	err = collection.FindOne(context.TODO(), bson.M{"_id": postID}).Decode(&post)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "Post no encontrado"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar el post"})
+	}
+	if post.AutorID != autorID {
+		return c.JSON(http.StatusForbidden, echo.Map{"message": "Solo el autor puede editar el post"})
+	}
+	if post.RepostDe != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Un repost no se puede editar"})
+	}
+	// Antes de publicarse no hay historial ni notificaciones: se envían al publicar
+	publicado := utils.EstaPublicado(post)
+
+	cambios := bson.M{}
+	if body.Titulo != nil && *body.Titulo != post.Titulo {
+		cambios["titulo"] = *body.Titulo
+	}
+	if body.Contenido != nil && *body.Contenido != post.Contenido {
+		cambios["contenido"] = *body.Contenido
+		menciones, err := utils.ResolverMenciones(*body.Contenido, autorID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al resolver menciones"})
+		}
+		cambios["menciones"] = menciones
+	}
+	if body.Categoria != nil && *body.Categoria != post.Categoria {
+		cambios["categoria"] = *body.Categoria
+	}
+	// Los hashtags del contenido siempre forman parte de los tags
+	tags, contenido := post.Tags, post.Contenido
+	if body.Tags != nil {
+		tags = *body.Tags
+	}
+	if body.Contenido != nil {
+		contenido = *body.Contenido
+	}
+	nuevosTags := utils.UnirTags(tags, utils.ExtraerHashtags(contenido))
+	if !slices.Equal(nuevosTags, post.Tags) {
+		cambios["tags"] = nuevosTags
+	}
+	if len(cambios) == 0 {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "No hay cambios que guardar"})
+	}
+	if body.Titulo != nil || body.Contenido != nil {
+		titulo, contenido := post.Titulo, post.Contenido
+		if body.Titulo != nil {
+			titulo = *body.Titulo
+		}
+		if body.Contenido != nil {
+			contenido = *body.Contenido
+		}
+		cambios["idioma"] = utils.DetectarIdioma(titulo + " " + contenido)
+	}
+
+	// Guardar la versión anterior antes de sobrescribirla
+	mencionesAnteriores := post.Menciones
+	ahora := time.Now()
+	revision := models.PostRevision{
+		ID:        primitive.NewObjectID(),
+		PostID:    post.ID,
+		Titulo:    post.Titulo,
+		Contenido: post.Contenido,
+		Categoria: post.Categoria,
+		Tags:      post.Tags,
+		Fecha:     ahora,
+	}
+	if publicado {
+		if _, err := config.GetCollection("post_revisiones").InsertOne(context.TODO(), revision); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al guardar el historial"})
+		}
+		cambios["editado"] = true
+		cambios["fechaEdicion"] = ahora
+	}
+
+	err = collection.FindOneAndUpdate(context.TODO(),
+		bson.M{"_id": postID},
+		bson.M{"$set": cambios},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&post)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al actualizar el post"})
+	}
+	if !publicado {
+		return c.JSON(http.StatusOK, post)
+	}
+	if _, ok := cambios["tags"]; ok {
+		utils.RegistrarUsoTags(tagsAgregados(revision.Tags, post.Tags), post.ID, autorID, "post")
+	}
+	// Solo se notifica a quienes no estaban mencionados antes de la edición
+	if _, ok := cambios["menciones"]; ok {
+		utils.NotificarMenciones(post.Menciones, autorID, post.ID, "Te mencionó en un post", mencionesAnteriores)
+	}
+
+	return c.JSON(http.StatusOK, post)
+}
+
+// VerRevisionesPost devuelve, de la más reciente a la más antigua, las versiones
+// anteriores de un post editado.
+func VerRevisionesPost(c echo.Context) error {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID del post inválido"})
+	}
+	cursor, err := utils.DecodificarCursor(c.QueryParam("cursor"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Cursor inválido"})
+	}
+	viewerID, err := viewerDesdeQuery(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "viewerId inválido"})
+	}
+	limite := utils.ParsearLimite(c.QueryParam("limit"), 20, 100)
+	if !postVisible(c, viewerID, postID) {
+		return nil
+	}
 
-	// Enviar notificación solo si el usuario que da like es diferente al autor del post
-	if post.AutorID != userID {
-		utils.CrearNotificacion("like", userID, post.AutorID, "Le dio like a tu post", &postID)
-	} else {
-		log.Println("No se envía notificación de like (usuario es el autor del post)")
+	filtro := bson.M{"postId": postID}
+	if cursor != nil {
+		filtro = bson.M{"$and": []bson.M{filtro, utils.FiltroDespuesDe("fecha", cursor)}}
 	}
 
-	return c.JSON(http.StatusCreated, echo.Map{"message": "Like registrado"})
+	opts := options.Find().
+		SetSort(bson.D{{Key: "fecha", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limite + 1))
+	cur, err := config.GetCollection("post_revisiones").Find(context.TODO(), filtro, opts)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar el historial"})
+	}
+	defer cur.Close(context.TODO())
+
+	revisiones := []models.PostRevision{}
+	if err := cur.All(context.TODO(), &revisiones); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer el historial"})
+	}
+
+	var siguiente string
+	if len(revisiones) > limite {
+		revisiones = revisiones[:limite]
+		ultima := revisiones[limite-1]
+		siguiente = utils.CodificarCursor(ultima.Fecha, ultima.ID)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": revisiones, "nextCursor": siguiente})
 }
 
 // CrearComentario agrega un comentario a un post y envía una notificación al autor del post si corresponde.
@@ -213,6 +635,7 @@ func CrearComentario(c echo.Context) error {
 	var body struct {
 		AutorID   string `json:"autorId"`
 		Contenido string `json:"contenido"`
+		ParentID  string `json:"parentId"` // opcional: comentario al que se responde
 	}
 
 	// Parsear el cuerpo de la solicitud
@@ -226,42 +649,121 @@ func CrearComentario(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, echo.Map{"message": "autorId inválido"})
 	}
 
+	// Obtener el post original para identificar al autor del post
+	var post models.Post
+	err = config.GetCollection("posts").FindOne(context.TODO(), bson.M{"_id": postID}).Decode(&post)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "Post no encontrado"})
+	}
+
+	// No se puede comentar en posts de usuarios con los que hay un bloqueo
+	bloqueado, err := utils.HayBloqueo(autorID, post.AutorID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar bloqueos"})
+	}
+	if bloqueado {
+		return c.JSON(http.StatusForbidden, echo.Map{"message": "No puedes interactuar con este usuario"})
+	}
+	if !postVisible(c, autorID, postID) {
+		return nil
+	}
+	if post.ComentariosBloqueados {
+		return c.JSON(http.StatusForbidden, echo.Map{"message": "Los comentarios de este post están desactivados"})
+	}
+
+	// Si es una respuesta, el comentario padre debe ser de este post
+	collection := config.GetCollection("comentarios")
+	var respondido *models.Comentario
+	if body.ParentID != "" {
+		parentID, err := primitive.ObjectIDFromHex(body.ParentID)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"message": "parentId inválido"})
+		}
+		respondido = &models.Comentario{}
+		err = collection.FindOne(context.TODO(), bson.M{"_id": parentID, "postId": postID}).Decode(respondido)
+		if err == mongo.ErrNoDocuments {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "Comentario padre no encontrado"})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar el comentario padre"})
+		}
+		if respondido.Eliminado {
+			return c.JSON(http.StatusBadRequest, echo.Map{"message": "No se puede responder a un comentario eliminado"})
+		}
+		bloqueado, err := utils.HayBloqueo(autorID, respondido.AutorID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar bloqueos"})
+		}
+		if bloqueado {
+			return c.JSON(http.StatusForbidden, echo.Map{"message": "No puedes interactuar con este usuario"})
+		}
+	}
+
+	menciones, err := utils.ResolverMenciones(body.Contenido, autorID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al resolver menciones"})
+	}
+
 	// Crear el objeto Comentario con los datos recibidos
 	comentario := models.Comentario{
 		ID:        primitive.NewObjectID(),
 		PostID:    postID,
 		AutorID:   autorID,
 		Contenido: body.Contenido,
+		Menciones: menciones,
 		Fecha:     time.Now(),
+		Idioma:    utils.DetectarIdioma(body.Contenido),
+	}
+	if respondido != nil {
+		// Pasada la profundidad máxima la respuesta queda al mismo nivel que el comentario respondido
+		parentID, profundidad := respondido.ID, respondido.Profundidad+1
+		if respondido.Profundidad >= maxProfundidadComentarios && respondido.ParentID != nil {
+			parentID, profundidad = *respondido.ParentID, respondido.Profundidad
+		}
+		comentario.ParentID = &parentID
+		comentario.Profundidad = profundidad
 	}
 
 	// Guardar el comentario en la colección
-	collection := config.GetCollection("comentarios")
 	_, err = collection.InsertOne(context.TODO(), comentario)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al guardar el comentario"})
 	}
-
-	// Obtener el post original para identificar al autor del post
-	var post models.Post
-	err = config.GetCollection("posts").FindOne(context.TODO(), bson.M{"_id": postID}).Decode(&post)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "No se pudo verificar el autor del post"})
+	utils.IncrementarContador(postID, "totalComentarios", 1)
+	if comentario.ParentID != nil {
+		_, err = collection.UpdateOne(context.TODO(),
+			bson.M{"_id": *comentario.ParentID},
+			bson.M{"$inc": bson.M{"totalRespuestas": 1}},
+		)
+		if err != nil {
+			log.Println("Error actualizando totalRespuestas del comentario:", err)
+		}
 	}
 
+	// Los hashtags del comentario se suman a los tags del post
+	sumarTagsAlPost(postID, autorID, utils.ExtraerHashtags(comentario.Contenido))
+
 	// Mostrar logs de depuración
 	log.Println("Autor del comentario:", comentario.AutorID.Hex())
 	log.Println("Autor del post:", post.AutorID.Hex())
 
+	// Notificar al autor del comentario respondido
+	if respondido != nil && respondido.AutorID != comentario.AutorID {
+		utils.CrearNotificacion("respuesta", comentario.AutorID, respondido.AutorID, "Respondió tu comentario", &postID)
+	}
+
 	// Enviar notificación solo si el autor del comentario no es el mismo del post
-	if comentario.AutorID != post.AutorID {
+	// (ni acaba de recibir la de respuesta)
+	if comentario.AutorID != post.AutorID && (respondido == nil || respondido.AutorID != post.AutorID) {
 		utils.CrearNotificacion("comentario", comentario.AutorID, post.AutorID, "Comentó tu post", &postID)
 	}
+	utils.NotificarMenciones(comentario.Menciones, autorID, postID, "Te mencionó en un comentario", nil)
 
 	return c.JSON(http.StatusCreated, comentario)
 }
 
-// ObtenerComentarios devuelve todos los comentarios de un post
+// ObtenerComentarios devuelve paginados los comentarios de primer nivel de un post
+// (?orden=antiguos|recientes|top, ?viewerId=)
 func ObtenerComentarios(c echo.Context) error {
 	postIDParam := c.Param("id")
 	postID, err := primitive.ObjectIDFromHex(postIDParam)
@@ -269,17 +771,39 @@ func ObtenerComentarios(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID del post inválido"})
 	}
 
-	collection := config.GetCollection("comentarios")
-	cursor, err := collection.Find(context.TODO(), bson.M{"postId": postID})
+	viewerID, err := viewerDesdeQuery(c)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar los comentarios"})
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "viewerId inválido"})
+	}
+	if !postVisible(c, viewerID, postID) {
+		return nil
 	}
-	defer cursor.Close(context.TODO())
 
-	var comentarios []models.Comentario
-	if err := cursor.All(context.TODO(), &comentarios); err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer los comentarios"})
+	// Solo los de primer nivel; las respuestas se piden con VerRespuestas
+	return paginarComentarios(c, bson.M{"postId": postID, "parentId": nil})
+}
+
+// tagsAgregados devuelve los tags de despues que no estaban en antes.
+func tagsAgregados(antes, despues []string) []string {
+	previos := map[string]bool{}
+	for _, t := range antes {
+		previos[t] = true
+	}
+	var nuevos []string
+	for _, t := range despues {
+		if !previos[t] {
+			nuevos = append(nuevos, t)
+		}
 	}
+	return nuevos
+}
 
-	return c.JSON(http.StatusOK, comentarios)
+// viewerDesdeQuery lee el parámetro opcional ?viewerId= que identifica a quien consulta.
+// Si no se envía devuelve el ObjectID cero (visitante anónimo).
+func viewerDesdeQuery(c echo.Context) (primitive.ObjectID, error) {
+	v := c.QueryParam("viewerId")
+	if v == "" {
+		return primitive.NilObjectID, nil
+	}
+	return primitive.ObjectIDFromHex(v)
 }
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"post-service/config"
+	"post-service/models"
+	"post-service/utils"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CambiarEstado publica, programa o vuelve a borrador el post :id ({autorId,
+// status, publishAt}). Solo aplica a posts que todavía no se publicaron: un post
+// publicado no puede volver atrás.
+func CambiarEstado(c echo.Context) error {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID del post inválido"})
+	}
+	var body struct {
+		AutorID    string     `json:"autorId"`
+		Estado     string     `json:"status"`
+		PublicarEn *time.Time `json:"publishAt"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Error al parsear el cuerpo JSON"})
+	}
+	autorID, err := primitive.ObjectIDFromHex(body.AutorID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de autor inválido"})
+	}
+	if body.Estado == "" && body.PublicarEn == nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Falta el estado"})
+	}
+	estado, publicarEn, ok := estadoPedido(c, body.Estado, body.PublicarEn)
+	if !ok {
+		return nil
+	}
+
+	ahora := time.Now()
+	var actualizacion bson.M
+	switch estado {
+	case models.EstadoPublicado:
+		actualizacion = bson.M{
+			"$set":   bson.M{"estado": estado, "fechaCreado": ahora, "difundiendo": ahora},
+			"$unset": bson.M{"publicarEn": ""},
+		}
+	case models.EstadoProgramado:
+		actualizacion = bson.M{"$set": bson.M{"estado": estado, "publicarEn": *publicarEn, "fechaCreado": *publicarEn}}
+	default:
+		actualizacion = bson.M{
+			"$set":   bson.M{"estado": estado},
+			"$unset": bson.M{"publicarEn": ""},
+		}
+	}
+
+	// El filtro por estado evita competir con el publicador: si ya tomó el post,
+	// este update no encuentra nada
+	var post models.Post
+	err = config.GetCollection("posts").FindOneAndUpdate(context.TODO(),
+		bson.M{
+			"_id":     postID,
+			"autorId": autorID,
+			"estado":  bson.M{"$in": bson.A{models.EstadoBorrador, models.EstadoProgramado}},
+		},
+		actualizacion,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&post)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "No tienes un borrador o post programado con ese ID"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al cambiar el estado del post"})
+	}
+
+	if estado == models.EstadoPublicado {
+		go utils.Difundir(post)
+	}
+	return c.JSON(http.StatusOK, post)
+}
+
+// VerBorradores devuelve, paginados, los borradores y posts programados de :id.
+// Solo los ve su autor (?viewerId= debe ser :id). Admite los mismos ?orden=,
+// ?limit= y ?cursor= que los demás listados.
+func VerBorradores(c echo.Context) error {
+	autorID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID inválido"})
+	}
+	viewerID, err := viewerDesdeQuery(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "viewerId inválido"})
+	}
+	if viewerID != autorID {
+		return c.JSON(http.StatusForbidden, echo.Map{"message": "Solo el autor puede ver sus borradores"})
+	}
+	return paginarPosts(c, bson.M{
+		"autorId": autorID,
+		"estado":  bson.M{"$in": bson.A{models.EstadoBorrador, models.EstadoProgramado}},
+	})
+}
+
+// estadoPedido valida el estado (y la fecha de publicación, si corresponde) que se
+// pide para un post. Vacío equivale a published, o a scheduled si viene
+// publishAt. Si no es válido responde al cliente y devuelve ok = false.
+func estadoPedido(c echo.Context, estado string, publicarEn *time.Time) (string, *time.Time, bool) {
+	if estado == "" {
+		estado = models.EstadoPublicado
+		if publicarEn != nil {
+			estado = models.EstadoProgramado
+		}
+	}
+	switch estado {
+	case models.EstadoProgramado:
+		if publicarEn == nil || !publicarEn.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, echo.Map{"message": "publishAt debe ser una fecha futura"})
+			return "", nil, false
+		}
+		return estado, publicarEn, true
+	case models.EstadoBorrador, models.EstadoPublicado:
+		if publicarEn != nil {
+			c.JSON(http.StatusBadRequest, echo.Map{"message": "publishAt solo se usa con status scheduled"})
+			return "", nil, false
+		}
+		return estado, nil, true
+	}
+	c.JSON(http.StatusBadRequest, echo.Map{"message": "Estado no válido (draft, scheduled, published)"})
+	return "", nil, false
+}
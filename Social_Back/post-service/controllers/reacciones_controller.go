@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"post-service/config"
+	"post-service/models"
+	"post-service/utils"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// objetivoReaccion es el post o comentario al que se reacciona.
+type objetivoReaccion struct {
+	postID       primitive.ObjectID
+	comentarioID *primitive.ObjectID // nil si se reacciona al post
+	autorID      primitive.ObjectID  // autor del post o comentario
+}
+
+// VerTiposReaccion devuelve las reacciones permitidas (configurables con REACCIONES).
+func VerTiposReaccion(c echo.Context) error {
+	return c.JSON(http.StatusOK, echo.Map{"data": utils.TiposReaccion()})
+}
+
+// FijarReaccionPost deja la reacción {usuarioId, tipo} en el post :id.
+func FijarReaccionPost(c echo.Context) error {
+	objetivo, ok := postAReaccionar(c)
+	if !ok {
+		return nil
+	}
+	return fijarReaccion(c, objetivo, "")
+}
+
+// QuitarReaccionPost quita la reacción de {usuarioId} al post :id.
+func QuitarReaccionPost(c echo.Context) error {
+	objetivo, ok := postAReaccionar(c)
+	if !ok {
+		return nil
+	}
+	return quitarReaccion(c, objetivo)
+}
+
+// DarLike equivale a fijar la reacción "like" en el post :id.
+func DarLike(c echo.Context) error {
+	objetivo, ok := postAReaccionar(c)
+	if !ok {
+		return nil
+	}
+	return fijarReaccion(c, objetivo, "like")
+}
+
+// QuitarLike quita la reacción de {usuarioId} al post :id, sea del tipo que sea.
+func QuitarLike(c echo.Context) error {
+	return QuitarReaccionPost(c)
+}
+
+// VerReaccionesPost lista quién reaccionó al post :id (?tipo= filtra por reacción).
+func VerReaccionesPost(c echo.Context) error {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID del post inválido"})
+	}
+	return listarReacciones(c, postID, utils.FiltroReaccion(postID, nil))
+}
+
+// FijarReaccionComentario deja la reacción {usuarioId, tipo} en el comentario :commentId.
+func FijarReaccionComentario(c echo.Context) error {
+	objetivo, ok := comentarioAReaccionar(c)
+	if !ok {
+		return nil
+	}
+	return fijarReaccion(c, objetivo, "")
+}
+
+// QuitarReaccionComentario quita la reacción de {usuarioId} al comentario :commentId.
+func QuitarReaccionComentario(c echo.Context) error {
+	objetivo, ok := comentarioAReaccionar(c)
+	if !ok {
+		return nil
+	}
+	return quitarReaccion(c, objetivo)
+}
+
+// VerReaccionesComentario lista quién reaccionó al comentario :commentId.
+func VerReaccionesComentario(c echo.Context) error {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID del post inválido"})
+	}
+	comentarioID, err := primitive.ObjectIDFromHex(c.Param("commentId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID del comentario inválido"})
+	}
+	return listarReacciones(c, postID, utils.FiltroReaccion(postID, &comentarioID))
+}
+
+// fijarReaccion aplica la reacción del cuerpo {usuarioId, tipo}; si tipoFijo no es
+// vacío se usa ese tipo. Solo la primera reacción genera notificación.
+func fijarReaccion(c echo.Context, objetivo objetivoReaccion, tipoFijo string) error {
+	var body struct {
+		UsuarioID string `json:"usuarioId"`
+		Tipo      string `json:"tipo"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Error al parsear el cuerpo JSON"})
+	}
+	usuarioID, err := primitive.ObjectIDFromHex(body.UsuarioID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de usuario inválido"})
+	}
+	tipo := tipoFijo
+	if tipo == "" {
+		tipo = body.Tipo
+	}
+	if !utils.EsTipoReaccion(tipo) {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Tipo de reacción no válido", "tipos": utils.TiposReaccion()})
+	}
+
+	// No se puede reaccionar a contenido de usuarios con los que hay un bloqueo
+	bloqueado, err := utils.HayBloqueo(usuarioID, objetivo.autorID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar bloqueos"})
+	}
+	if bloqueado {
+		return c.JSON(http.StatusForbidden, echo.Map{"message": "No puedes interactuar con este usuario"})
+	}
+	if !postVisible(c, usuarioID, objetivo.postID) {
+		return nil
+	}
+
+	anterior, err := utils.FijarReaccion(objetivo.postID, objetivo.comentarioID, usuarioID, tipo)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al guardar la reacción"})
+	}
+
+	if anterior == "" && usuarioID != objetivo.autorID {
+		tipoNoti, mensaje := "reaccion", "Reaccionó a tu post"
+		switch {
+		case objetivo.comentarioID != nil && tipo == "like":
+			tipoNoti, mensaje = "like_comentario", "Le gustó tu comentario"
+		case objetivo.comentarioID != nil:
+			mensaje = "Reaccionó a tu comentario"
+		case tipo == "like":
+			tipoNoti, mensaje = "like", "Le dio like a tu post"
+		}
+		utils.CrearNotificacion(tipoNoti, usuarioID, objetivo.autorID, mensaje, &objetivo.postID)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"reaccion": tipo, "anterior": anterior})
+}
+
+// quitarReaccion borra la reacción de {usuarioId}; no falla si no existía.
+func quitarReaccion(c echo.Context, objetivo objetivoReaccion) error {
+	var body struct {
+		UsuarioID string `json:"usuarioId"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Error al parsear el cuerpo JSON"})
+	}
+	usuarioID, err := primitive.ObjectIDFromHex(body.UsuarioID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de usuario inválido"})
+	}
+
+	anterior, err := utils.QuitarReaccion(objetivo.postID, objetivo.comentarioID, usuarioID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al quitar la reacción"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"reaccion": nil, "anterior": anterior})
+}
+
+// listarReacciones pagina, de la más reciente a la más antigua, las reacciones que
+// cumplen filtro con el perfil público de quien reaccionó, si el viewer puede ver
+// el post postID.
+func listarReacciones(c echo.Context, postID primitive.ObjectID, filtro bson.M) error {
+	cursor, err := utils.DecodificarCursor(c.QueryParam("cursor"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Cursor inválido"})
+	}
+	viewerID, err := viewerDesdeQuery(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "viewerId inválido"})
+	}
+	if !postVisible(c, viewerID, postID) {
+		return nil
+	}
+	limite := utils.ParsearLimite(c.QueryParam("limit"), 20, 100)
+
+	if tipo := c.QueryParam("tipo"); tipo != "" {
+		if !utils.EsTipoReaccion(tipo) {
+			return c.JSON(http.StatusBadRequest, echo.Map{"message": "Tipo de reacción no válido", "tipos": utils.TiposReaccion()})
+		}
+		filtro["tipo"] = tipo
+	}
+	bloqueados, err := utils.UsuariosBloqueados(viewerID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar bloqueos"})
+	}
+	if len(bloqueados) > 0 {
+		filtro["usuarioId"] = bson.M{"$nin": bloqueados}
+	}
+	if cursor != nil {
+		filtro = bson.M{"$and": []bson.M{filtro, utils.FiltroDespuesDe("fecha", cursor)}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filtro}},
+		{{Key: "$sort", Value: bson.D{{Key: "fecha", Value: -1}, {Key: "_id", Value: -1}}}},
+		// Se pide uno extra para saber si existe una página siguiente
+		{{Key: "$limit", Value: limite + 1}},
+	}
+	pipeline = append(pipeline, etapasUsuarioPublico("usuarioId", "usuario")...)
+
+	cur, err := config.GetCollection("reacciones").Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar las reacciones"})
+	}
+	defer cur.Close(context.TODO())
+
+	reacciones := []models.ReaccionDetalle{}
+	if err := cur.All(context.TODO(), &reacciones); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer las reacciones"})
+	}
+
+	var siguiente string
+	if len(reacciones) > limite {
+		reacciones = reacciones[:limite]
+		ultima := reacciones[limite-1]
+		siguiente = utils.CodificarCursor(ultima.Fecha, ultima.ID)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": reacciones, "nextCursor": siguiente})
+}
+
+// postAReaccionar busca el post :id. Si no existe responde al cliente y devuelve ok = false.
+func postAReaccionar(c echo.Context) (objetivoReaccion, bool) {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, echo.Map{"message": "ID del post inválido"})
+		return objetivoReaccion{}, false
+	}
+	var post models.Post
+	err = config.GetCollection("posts").FindOne(context.TODO(), bson.M{"_id": postID}).Decode(&post)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, echo.Map{"message": "Post no encontrado"})
+		return objetivoReaccion{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar el post"})
+		return objetivoReaccion{}, false
+	}
+	return objetivoReaccion{postID: post.ID, autorID: post.AutorID}, true
+}
+
+// comentarioAReaccionar busca el comentario :commentId del post :id.
+func comentarioAReaccionar(c echo.Context) (objetivoReaccion, bool) {
+	comentario, ok := comentarioDeRuta(c)
+	if !ok {
+		return objetivoReaccion{}, false
+	}
+	return objetivoReaccion{postID: comentario.PostID, comentarioID: &comentario.ID, autorID: comentario.AutorID}, true
+}
@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"post-service/config"
+	"post-service/models"
+	"post-service/utils"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Repostear comparte el post :id con los seguidores de {usuarioId}. Compartir un
+// repost comparte su original, y repetirlo no crea un segundo repost.
+func Repostear(c echo.Context) error {
+	usuarioID, ok := usuarioDelCuerpo(c)
+	if !ok {
+		return nil
+	}
+	original, ok := postACompartir(c, c.Param("id"), usuarioID)
+	if !ok {
+		return nil
+	}
+
+	repost, creado, err := utils.Repostear(usuarioID, original)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al compartir el post"})
+	}
+	if !creado {
+		return c.JSON(http.StatusOK, repost)
+	}
+	if usuarioID != original.AutorID {
+		utils.CrearNotificacion("repost", usuarioID, original.AutorID, "Compartió tu post", &original.ID)
+	}
+	repost.Original = &original
+	return c.JSON(http.StatusCreated, repost)
+}
+
+// DeshacerRepost deja de compartir el post :id (o el original, si :id es un repost).
+func DeshacerRepost(c echo.Context) error {
+	usuarioID, ok := usuarioDelCuerpo(c)
+	if !ok {
+		return nil
+	}
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID del post inválido"})
+	}
+	var post models.Post
+	err = config.GetCollection("posts").FindOne(context.TODO(), bson.M{"_id": postID}).Decode(&post)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar el post"})
+	}
+	if post.RepostDe != nil {
+		postID = *post.RepostDe
+	}
+
+	// El original puede haberse eliminado: el repost se busca igual
+	quitado, err := utils.DeshacerRepost(usuarioID, postID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al dejar de compartir el post"})
+	}
+	if !quitado {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "No compartiste este post"})
+	}
+	return c.JSON(http.StatusOK, echo.Map{"message": "Dejaste de compartir el post"})
+}
+
+// usuarioDelCuerpo lee {usuarioId}. Si no es válido responde al cliente y
+// devuelve ok = false.
+func usuarioDelCuerpo(c echo.Context) (primitive.ObjectID, bool) {
+	var body struct {
+		UsuarioID string `json:"usuarioId"`
+	}
+	if err := c.Bind(&body); err != nil {
+		c.JSON(http.StatusBadRequest, echo.Map{"message": "Error al parsear el cuerpo JSON"})
+		return primitive.NilObjectID, false
+	}
+	usuarioID, err := primitive.ObjectIDFromHex(body.UsuarioID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de usuario inválido"})
+		return primitive.NilObjectID, false
+	}
+	return usuarioID, true
+}
+
+// postACompartir busca el post que usuarioID quiere compartir o citar (el original
+// si idParam es un repost) y verifica que pueda hacerlo: sin bloqueos, que pueda
+// verlo, que ya esté publicado y, si es ajeno, que sea público y no venga de una
+// cuenta privada. Si no, responde al cliente y devuelve ok = false.
+func postACompartir(c echo.Context, idParam string, usuarioID primitive.ObjectID) (models.Post, bool) {
+	postID, err := primitive.ObjectIDFromHex(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, echo.Map{"message": "ID del post inválido"})
+		return models.Post{}, false
+	}
+	var post models.Post
+	err = config.GetCollection("posts").FindOne(context.TODO(), bson.M{"_id": postID}).Decode(&post)
+	if err == nil && post.RepostDe != nil {
+		err = config.GetCollection("posts").FindOne(context.TODO(), bson.M{"_id": *post.RepostDe}).Decode(&post)
+	}
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, echo.Map{"message": "Post no encontrado"})
+		return models.Post{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar el post"})
+		return models.Post{}, false
+	}
+
+	bloqueado, err := utils.HayBloqueo(usuarioID, post.AutorID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar bloqueos"})
+		return models.Post{}, false
+	}
+	if bloqueado {
+		c.JSON(http.StatusForbidden, echo.Map{"message": "No puedes interactuar con este usuario"})
+		return models.Post{}, false
+	}
+	if !postVisible(c, usuarioID, post.ID) {
+		return models.Post{}, false
+	}
+	if !utils.EstaPublicado(post) {
+		c.JSON(http.StatusBadRequest, echo.Map{"message": "El post todavía no se publicó"})
+		return models.Post{}, false
+	}
+	if usuarioID != post.AutorID {
+		// Compartirlo lo mostraría a gente fuera de su audiencia
+		if post.Visibilidad != "" && post.Visibilidad != models.VisibilidadPublica {
+			c.JSON(http.StatusForbidden, echo.Map{"message": "Solo se pueden compartir posts públicos"})
+			return models.Post{}, false
+		}
+		ajustes, err := utils.ObtenerAjustes(post.AutorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar la privacidad"})
+			return models.Post{}, false
+		}
+		if ajustes.CuentaPrivada {
+			c.JSON(http.StatusForbidden, echo.Map{"message": "No se pueden compartir posts de una cuenta privada"})
+			return models.Post{}, false
+		}
+	}
+	return post, true
+}
+
+// completarPosts prepara los posts para viewerID: incluye en cada repost o cita el
+// original si puede verlo y marca cuáles guardó (también entre los originales).
+func completarPosts(viewerID primitive.ObjectID, posts ...*models.Post) error {
+	originales, err := utils.OriginalesVisibles(viewerID, posts)
+	if err != nil {
+		return err
+	}
+	todos := append([]*models.Post{}, posts...)
+	for _, p := range posts {
+		if id := utils.IDOriginal(*p); id != nil && originales[*id] != nil {
+			// Copia propia: el mismo original puede aparecer en varios posts
+			original := *originales[*id]
+			p.Original = &original
+			todos = append(todos, p.Original)
+		}
+	}
+	return marcarGuardados(viewerID, todos...)
+}
+
+// sinRepostsOcultos quita de un listado ya completado los reposts cuyo original no
+// existe o no es visible: sin él no tienen nada que mostrar.
+func sinRepostsOcultos(posts []models.Post) []models.Post {
+	visibles := posts[:0]
+	for _, p := range posts {
+		if p.RepostDe == nil || p.Original != nil {
+			visibles = append(visibles, p)
+		}
+	}
+	return visibles
+}
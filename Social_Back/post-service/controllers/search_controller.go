@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"post-service/config"
+	"post-service/models"
+	"post-service/utils"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Parámetros de la búsqueda de texto completo.
+const (
+	maxCandidatosBusqueda = 200 // coincidencias que se leen de posts y de comentarios
+	pesoComentarios       = 0.5 // relevancia de un comentario frente al propio post
+	largoFragmento        = 160 // runas por extracto resaltado
+)
+
+// Buscar realiza una búsqueda de texto completo (?q=) sobre título, contenido, tags,
+// texto de los documentos adjuntos y comentarios de los posts. Acepta los filtros de GET /posts (tipo, categoria,
+// tag) además de autorId, desde y hasta (YYYY-MM-DD o RFC3339), y ?idioma=es|en
+// para elegir el análisis de la consulta (por defecto se detecta).
+func Buscar(c echo.Context) error {
+	q := strings.TrimSpace(c.QueryParam("q"))
+	if q == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Se requiere el parámetro q"})
+	}
+	viewerID, err := viewerDesdeQuery(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "viewerId inválido"})
+	}
+	cursor, err := utils.DecodificarCursor(c.QueryParam("cursor"))
+	if err != nil || (cursor != nil && cursor.Orden != "busqueda") {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Cursor inválido"})
+	}
+	limite := utils.ParsearLimite(c.QueryParam("limit"), 20, 50)
+
+	filtro, err := filtroBusqueda(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+	visibles, err := utils.FiltroListado(viewerID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar la privacidad"})
+	}
+	filtro["$and"] = []bson.M{visibles}
+
+	idioma := utils.DetectarIdioma(q)
+	switch c.QueryParam("idioma") {
+	case "es":
+		idioma = utils.IdiomaEspanol
+	case "en":
+		idioma = utils.IdiomaIngles
+	}
+	texto := bson.M{"$search": q, "$language": idioma}
+	ctx := context.TODO()
+
+	// 1. Coincidencias en los propios posts
+	resultados := map[primitive.ObjectID]*models.ResultadoBusqueda{}
+	filtroPosts := bson.M{"$text": texto}
+	for k, v := range filtro {
+		filtroPosts[k] = v
+	}
+	opts := options.Find().
+		SetProjection(bson.M{"puntaje": bson.M{"$meta": "textScore"}}).
+		SetSort(bson.M{"puntaje": bson.M{"$meta": "textScore"}}).
+		SetLimit(maxCandidatosBusqueda)
+	cur, err := config.GetCollection("posts").Find(ctx, filtroPosts, opts)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar posts"})
+	}
+	var encontrados []struct {
+		models.Post `bson:",inline"`
+		Puntaje     float64 `bson:"puntaje"`
+	}
+	if err := cur.All(ctx, &encontrados); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer posts"})
+	}
+	for _, e := range encontrados {
+		resultados[e.ID] = &models.ResultadoBusqueda{Post: e.Post, Puntaje: e.Puntaje}
+	}
+
+	// 2. Coincidencias en comentarios: suman relevancia al post al que pertenecen.
+	// Como en ObtenerComentarios, no cuentan los eliminados ni los de usuarios con
+	// los que el viewer tiene un bloqueo
+	bloqueados, err := utils.UsuariosBloqueados(viewerID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar bloqueos"})
+	}
+	filtroComentarios := bson.M{"$text": texto, "eliminado": bson.M{"$ne": true}}
+	if len(bloqueados) > 0 {
+		filtroComentarios["autorId"] = bson.M{"$nin": bloqueados}
+	}
+	opts = options.Find().
+		SetProjection(bson.M{"postId": 1, "contenido": 1, "puntaje": bson.M{"$meta": "textScore"}}).
+		SetSort(bson.M{"puntaje": bson.M{"$meta": "textScore"}}).
+		SetLimit(maxCandidatosBusqueda)
+	cur, err = config.GetCollection("comentarios").Find(ctx, filtroComentarios, opts)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar comentarios"})
+	}
+	var comentarios []struct {
+		PostID    primitive.ObjectID `bson:"postId"`
+		Contenido string             `bson:"contenido"`
+		Puntaje   float64            `bson:"puntaje"`
+	}
+	if err := cur.All(ctx, &comentarios); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer comentarios"})
+	}
+	mejorComentario := map[primitive.ObjectID]string{}
+	puntajeComentarios := map[primitive.ObjectID]float64{}
+	faltantes := []primitive.ObjectID{}
+	for _, cm := range comentarios {
+		if _, ok := mejorComentario[cm.PostID]; !ok {
+			mejorComentario[cm.PostID] = cm.Contenido // vienen ordenados por relevancia
+			if resultados[cm.PostID] == nil {
+				faltantes = append(faltantes, cm.PostID)
+			}
+		}
+		puntajeComentarios[cm.PostID] += cm.Puntaje
+	}
+
+	// Los posts encontrados solo por sus comentarios también deben cumplir los filtros
+	if len(faltantes) > 0 {
+		filtroFaltantes := bson.M{"_id": bson.M{"$in": faltantes}}
+		for k, v := range filtro {
+			filtroFaltantes[k] = v
+		}
+		cur, err = config.GetCollection("posts").Find(ctx, filtroFaltantes)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar posts"})
+		}
+		var posts []models.Post
+		if err := cur.All(ctx, &posts); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer posts"})
+		}
+		for _, p := range posts {
+			resultados[p.ID] = &models.ResultadoBusqueda{Post: p}
+		}
+	}
+
+	lista := make([]*models.ResultadoBusqueda, 0, len(resultados))
+	for id, r := range resultados {
+		r.Puntaje += pesoComentarios * puntajeComentarios[id]
+		lista = append(lista, r)
+	}
+	sort.Slice(lista, func(i, j int) bool {
+		if lista[i].Puntaje != lista[j].Puntaje {
+			return lista[i].Puntaje > lista[j].Puntaje
+		}
+		return lista[i].FechaCreado.After(lista[j].FechaCreado)
+	})
+
+	// Paginación por posición dentro del ranking de relevancia
+	desde := 0
+	if cursor != nil {
+		desde = int(cursor.Valor)
+	}
+	if desde > len(lista) {
+		desde = len(lista)
+	}
+	hasta := desde + limite
+	if hasta > len(lista) {
+		hasta = len(lista)
+	}
+	pagina := make([]models.ResultadoBusqueda, 0, hasta-desde)
+	terminos := utils.Palabras(q)
+	for _, r := range lista[desde:hasta] {
+		r.Resaltados = models.FragmentosResaltados{
+			Titulo:     utils.ResaltarFragmento(r.Titulo, terminos, largoFragmento),
+			Contenido:  utils.ResaltarFragmento(r.Contenido, terminos, largoFragmento),
+			Comentario: utils.ResaltarFragmento(mejorComentario[r.ID], terminos, largoFragmento),
+		}
+		for _, t := range r.Tags {
+			if utils.ResaltarFragmento(t, terminos, len(t)) != "" {
+				r.Resaltados.Tags = append(r.Resaltados.Tags, t)
+			}
+		}
+		for _, a := range r.Adjuntos {
+			if r.Resaltados.Documento = utils.ResaltarFragmento(a.Texto, terminos, largoFragmento); r.Resaltados.Documento != "" {
+				break
+			}
+		}
+		pagina = append(pagina, *r)
+	}
+
+	enPagina := make([]*models.Post, len(pagina))
+	for i := range pagina {
+		enPagina[i] = &pagina[i].Post
+	}
+	if err := completarPosts(viewerID, enPagina...); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al completar los posts"})
+	}
+
+	var siguiente string
+	if hasta < len(lista) {
+		siguiente = utils.CodificarCursorConteo("busqueda", int64(hasta), lista[hasta-1].ID)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"data": pagina, "nextCursor": siguiente, "total": len(lista)})
+}
+
+// filtroBusqueda arma los filtros estructurados de la búsqueda a partir de la query.
+func filtroBusqueda(c echo.Context) (bson.M, error) {
+	filtro := bson.M{"repostDe": nil}
+	if tipo := c.QueryParam("tipo"); tipo != "" {
+		filtro["tipo"] = tipo
+	}
+	if categoria := c.QueryParam("categoria"); categoria != "" {
+		filtro["categoria"] = categoria
+	}
+	if tag := c.QueryParam("tag"); tag != "" {
+		filtro["tags"] = utils.NormalizarTag(tag)
+	}
+	if v := c.QueryParam("autorId"); v != "" {
+		autor, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return nil, errValidacion("autorId inválido")
+		}
+		filtro["autorId"] = autor
+	}
+
+	rango := bson.M{}
+	if v := c.QueryParam("desde"); v != "" {
+		t, err := parsearFecha(v)
+		if err != nil {
+			return nil, errValidacion("Fecha desde inválida")
+		}
+		rango["$gte"] = t
+	}
+	if v := c.QueryParam("hasta"); v != "" {
+		t, err := parsearFecha(v)
+		if err != nil {
+			return nil, errValidacion("Fecha hasta inválida")
+		}
+		// Una fecha sin hora incluye todo ese día
+		if len(v) == len("2006-01-02") {
+			t = t.Add(24 * time.Hour)
+		}
+		rango["$lt"] = t
+	}
+	if len(rango) > 0 {
+		filtro["fechaCreado"] = rango
+	}
+	return filtro, nil
+}
+
+// parsearFecha acepta fechas YYYY-MM-DD o RFC3339.
+func parsearFecha(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
+
+// errValidacion es un error cuyo mensaje se puede devolver tal cual al cliente.
+type errValidacion string
+
+func (e errValidacion) Error() string { return string(e) }
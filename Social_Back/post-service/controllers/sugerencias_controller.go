@@ -0,0 +1,347 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"post-service/config"
+	"post-service/models"
+	"post-service/utils"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Pesos de cada señal en el puntaje de una sugerencia.
+const (
+	pesoSeguidoEnComun = 3.0 // por cada persona que sigo y que ya lo sigue
+	pesoInstitucion    = 2.0 // misma institución o cohorte según el código institucional
+	pesoTagEnComun     = 1.5 // por cada tag que ambos usamos
+	pesoActividad      = 1.0 // multiplicado por log(1 + posts recientes)
+)
+
+// candidatoSugerencia acumula las señales encontradas para un posible usuario a seguir.
+type candidatoSugerencia struct {
+	seguidosEnComun int
+	mismoGrupo      string // "institución" o "cohorte"
+	tags            []string
+	postsRecientes  int
+}
+
+// puntaje combina las señales del candidato y devuelve también el motivo principal.
+func (cs *candidatoSugerencia) puntaje() (float64, string) {
+	porComun := pesoSeguidoEnComun * float64(cs.seguidosEnComun)
+	porInstitucion := 0.0
+	if cs.mismoGrupo != "" {
+		porInstitucion = pesoInstitucion
+	}
+	porTags := pesoTagEnComun * float64(len(cs.tags))
+	total := porComun + porInstitucion + porTags + pesoActividad*math.Log1p(float64(cs.postsRecientes))
+
+	switch {
+	case porComun > 0 && porComun >= porInstitucion && porComun >= porTags:
+		if cs.seguidosEnComun == 1 {
+			return total, "Seguido por 1 persona que sigues"
+		}
+		return total, fmt.Sprintf("Seguido por %d personas que sigues", cs.seguidosEnComun)
+	case porInstitucion > 0 && porInstitucion >= porTags:
+		return total, "De tu misma " + cs.mismoGrupo
+	case porTags > 0:
+		return total, "También publica sobre #" + cs.tags[0]
+	default:
+		return total, "Activo recientemente"
+	}
+}
+
+// maxCandidatosCohorte limita cuántos usuarios de la misma cohorte se consideran:
+// en una institución grande el prefijo puede abarcar miles de cuentas.
+const maxCandidatosCohorte = 200
+
+// largoPrefijoCohorte indica cuántos caracteres iniciales de un código de matrícula
+// identifican el departamento/cohorte. Por defecto son 4 porque los códigos de
+// matrícula empiezan con el año de ingreso (p. ej. "2021" en "20210345"); las
+// instituciones con otro formato lo ajustan con SUGERENCIAS_PREFIJO_COHORTE. Los
+// códigos con forma de correo no tienen cohorte: se agrupan por utils.Institucion.
+func largoPrefijoCohorte() int {
+	if n, err := strconv.Atoi(os.Getenv("SUGERENCIAS_PREFIJO_COHORTE")); err == nil && n > 0 {
+		return n
+	}
+	return 4
+}
+
+// VerSugerencias recomienda usuarios para seguir combinando amigos de amigos,
+// institución/cohorte compartida, tags en común y actividad reciente.
+func VerSugerencias(c echo.Context) error {
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de usuario inválido"})
+	}
+	limite := utils.ParsearLimite(c.QueryParam("limit"), 10, 50)
+	ctx := context.TODO()
+
+	seguidos, excluidos, err := excluidosDeSugerencias(userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al preparar sugerencias"})
+	}
+	candidatos := map[primitive.ObjectID]*candidatoSugerencia{}
+	candidato := func(id primitive.ObjectID) *candidatoSugerencia {
+		if candidatos[id] == nil {
+			candidatos[id] = &candidatoSugerencia{}
+		}
+		return candidatos[id]
+	}
+
+	// 1. Amigos de amigos: a quién siguen las personas que sigo
+	if len(seguidos) > 0 {
+		cur, err := config.GetCollection("follows").Aggregate(ctx, mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"seguidorId": bson.M{"$in": seguidos}, "seguidoId": bson.M{"$nin": excluidos}}}},
+			{{Key: "$group", Value: bson.M{"_id": "$seguidoId", "n": bson.M{"$sum": 1}}}},
+			{{Key: "$sort", Value: bson.M{"n": -1}}},
+			{{Key: "$limit", Value: 200}},
+		})
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al calcular sugerencias"})
+		}
+		var filas []struct {
+			ID primitive.ObjectID `bson:"_id"`
+			N  int                `bson:"n"`
+		}
+		if err := cur.All(ctx, &filas); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al calcular sugerencias"})
+		}
+		for _, f := range filas {
+			candidato(f.ID).seguidosEnComun = f.N
+		}
+	}
+
+	// 2. Misma institución (con la regla de utils.Institucion) o, si el código es
+	// de matrícula, mismo departamento/cohorte según su prefijo
+	var yo struct {
+		Codigo string `bson:"codigo_institucional"`
+	}
+	err = config.GetCollection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&yo)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al calcular sugerencias"})
+	}
+	var grupo, patron string
+	if institucion := utils.Institucion(yo.Codigo); institucion != "" {
+		grupo, patron = "institución", "(?i)@"+regexp.QuoteMeta(institucion)+`\s*$`
+	} else if n := largoPrefijoCohorte(); len(yo.Codigo) >= n {
+		grupo, patron = "cohorte", "^"+regexp.QuoteMeta(yo.Codigo[:n])+"[^@]*$"
+	}
+	if grupo != "" {
+		// Una muestra al azar: así no siempre se sugieren las mismas cuentas
+		cur, err := config.GetCollection("users").Aggregate(ctx, mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{
+				"codigo_institucional": bson.M{"$regex": patron},
+				"_id":                  bson.M{"$nin": excluidos},
+			}}},
+			{{Key: "$sample", Value: bson.M{"size": maxCandidatosCohorte}}},
+			{{Key: "$project", Value: bson.M{"_id": 1}}},
+		})
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al calcular sugerencias"})
+		}
+		var filas []struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cur.All(ctx, &filas); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al calcular sugerencias"})
+		}
+		for _, f := range filas {
+			candidato(f.ID).mismoGrupo = grupo
+		}
+	}
+
+	// 3. Tags en común: autores recientes que publican sobre mis tags más usados
+	misTags, err := tagsFrecuentes(userID, 10)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al calcular sugerencias"})
+	}
+	if len(misTags) > 0 {
+		cur, err := config.GetCollection("posts").Aggregate(ctx, mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{
+				"tags":        bson.M{"$in": misTags},
+				"autorId":     bson.M{"$nin": excluidos},
+				"fechaCreado": bson.M{"$gte": time.Now().AddDate(0, 0, -30)},
+				"estado":      bson.M{"$in": bson.A{nil, models.EstadoPublicado}},
+			}}},
+			{{Key: "$unwind", Value: "$tags"}},
+			{{Key: "$match", Value: bson.M{"tags": bson.M{"$in": misTags}}}},
+			{{Key: "$group", Value: bson.M{"_id": "$autorId", "tags": bson.M{"$addToSet": "$tags"}}}},
+			{{Key: "$limit", Value: 200}},
+		})
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al calcular sugerencias"})
+		}
+		var filas []struct {
+			ID   primitive.ObjectID `bson:"_id"`
+			Tags []string           `bson:"tags"`
+		}
+		if err := cur.All(ctx, &filas); err != nil {
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al calcular sugerencias"})
+		}
+		for _, f := range filas {
+			candidato(f.ID).tags = f.Tags
+		}
+	}
+
+	if len(candidatos) == 0 {
+		return c.JSON(http.StatusOK, []models.Sugerencia{})
+	}
+	ids := make([]primitive.ObjectID, 0, len(candidatos))
+	for id := range candidatos {
+		ids = append(ids, id)
+	}
+
+	// 4. Actividad reciente: posts de los candidatos en las últimas dos semanas
+	cur, err := config.GetCollection("posts").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"autorId":     bson.M{"$in": ids},
+			"fechaCreado": bson.M{"$gte": time.Now().AddDate(0, 0, -14)},
+			"estado":      bson.M{"$in": bson.A{nil, models.EstadoPublicado}},
+		}}},
+		{{Key: "$group", Value: bson.M{"_id": "$autorId", "n": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al calcular sugerencias"})
+	}
+	var actividad []struct {
+		ID primitive.ObjectID `bson:"_id"`
+		N  int                `bson:"n"`
+	}
+	if err := cur.All(ctx, &actividad); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al calcular sugerencias"})
+	}
+	for _, a := range actividad {
+		candidatos[a.ID].postsRecientes = a.N
+	}
+
+	// Perfil público de los candidatos (se descartan cuentas que ya no existen)
+	ucur, err := config.GetCollection("users").Find(ctx, bson.M{"_id": bson.M{"$in": ids}},
+		options.Find().SetProjection(proyeccionUsuarioPublico))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar usuarios"})
+	}
+	var usuarios []models.UsuarioPublico
+	if err := ucur.All(ctx, &usuarios); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer usuarios"})
+	}
+
+	sugerencias := make([]models.Sugerencia, 0, len(usuarios))
+	for _, u := range usuarios {
+		cs := candidatos[u.ID]
+		puntaje, motivo := cs.puntaje()
+		sugerencias = append(sugerencias, models.Sugerencia{
+			Usuario:          u,
+			Puntaje:          puntaje,
+			Motivo:           motivo,
+			SeguidosEnComun:  cs.seguidosEnComun,
+			MismaInstitucion: cs.mismoGrupo == "institución",
+			MismaCohorte:     cs.mismoGrupo == "cohorte",
+			TagsEnComun:      cs.tags,
+		})
+	}
+	sort.Slice(sugerencias, func(i, j int) bool { return sugerencias[i].Puntaje > sugerencias[j].Puntaje })
+	if len(sugerencias) > limite {
+		sugerencias = sugerencias[:limite]
+	}
+
+	return c.JSON(http.StatusOK, sugerencias)
+}
+
+// DescartarSugerencia evita que :candidatoId vuelva a sugerirse al usuario :id.
+func DescartarSugerencia(c echo.Context) error {
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de usuario inválido"})
+	}
+	candidatoID, err := primitive.ObjectIDFromHex(c.Param("candidatoId"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de sugerencia inválido"})
+	}
+
+	_, err = config.GetCollection("sugerencias_descartadas").UpdateOne(context.TODO(),
+		bson.M{"usuarioId": userID, "descartadoId": candidatoID},
+		bson.M{"$setOnInsert": models.SugerenciaDescartada{
+			ID:           primitive.NewObjectID(),
+			UsuarioID:    userID,
+			DescartadoID: candidatoID,
+			Fecha:        time.Now(),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al descartar la sugerencia"})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"message": "Sugerencia descartada"})
+}
+
+// excluidosDeSugerencias devuelve a quién sigue el usuario y la lista completa de
+// usuarios que nunca deben sugerírsele: él mismo, sus seguidos, solicitudes
+// pendientes, bloqueos y sugerencias descartadas.
+func excluidosDeSugerencias(userID primitive.ObjectID) (seguidos, excluidos []primitive.ObjectID, err error) {
+	ctx := context.TODO()
+
+	valores, err := config.GetCollection("follows").Distinct(ctx, "seguidoId", bson.M{"seguidorId": userID})
+	if err != nil {
+		return nil, nil, err
+	}
+	seguidos = utils.IDsDeDistinct(valores)
+
+	pendientes, err := config.GetCollection("follow_requests").Distinct(ctx, "seguidoId", bson.M{"seguidorId": userID})
+	if err != nil {
+		return nil, nil, err
+	}
+	descartados, err := config.GetCollection("sugerencias_descartadas").Distinct(ctx, "descartadoId", bson.M{"usuarioId": userID})
+	if err != nil {
+		return nil, nil, err
+	}
+	bloqueados, err := utils.UsuariosBloqueados(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	excluidos = append([]primitive.ObjectID{userID}, seguidos...)
+	excluidos = append(excluidos, utils.IDsDeDistinct(pendientes)...)
+	excluidos = append(excluidos, utils.IDsDeDistinct(descartados)...)
+	excluidos = append(excluidos, bloqueados...)
+	return seguidos, excluidos, nil
+}
+
+// tagsFrecuentes devuelve los tags que más usó el usuario en sus últimos posts.
+func tagsFrecuentes(userID primitive.ObjectID, maximo int) ([]string, error) {
+	cur, err := config.GetCollection("posts").Aggregate(context.TODO(), mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"autorId": userID}}},
+		{{Key: "$sort", Value: bson.M{"fechaCreado": -1}}},
+		{{Key: "$limit", Value: 100}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{"_id": "$tags", "n": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.M{"n": -1}}},
+		{{Key: "$limit", Value: maximo}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var filas []struct {
+		Tag string `bson:"_id"`
+	}
+	if err := cur.All(context.TODO(), &filas); err != nil {
+		return nil, err
+	}
+	tags := make([]string, 0, len(filas))
+	for _, f := range filas {
+		tags = append(tags, f.Tag)
+	}
+	return tags, nil
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"post-service/config"
+	"post-service/models"
+	"post-service/utils"
+	"sort"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ventanaTendencia define el periodo evaluado y contra qué periodo previo se compara.
+type ventanaTendencia struct {
+	actual time.Duration
+	base   time.Duration // historial inmediatamente anterior usado como línea base
+}
+
+// ventanasTendencias son los valores aceptados en ?ventana= de GET /tags/trending.
+var ventanasTendencias = map[string]ventanaTendencia{
+	"1h":  {actual: time.Hour, base: 24 * time.Hour},
+	"24h": {actual: 24 * time.Hour, base: 7 * 24 * time.Hour},
+	"7d":  {actual: 7 * 24 * time.Hour, base: 28 * 24 * time.Hour},
+}
+
+// minimoUsuariosTendencia evita que un tag usado por una o dos personas sea tendencia.
+const minimoUsuariosTendencia = 3
+
+// VerPostsPorTag lista, paginados como GET /posts, los posts con el tag :tag.
+func VerPostsPorTag(c echo.Context) error {
+	tag := utils.NormalizarTag(c.Param("tag"))
+	if tag == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Tag inválido"})
+	}
+
+	viewerID, err := viewerDesdeQuery(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "viewerId inválido"})
+	}
+	visibles, err := utils.FiltroListado(viewerID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar la privacidad"})
+	}
+
+	return paginarPosts(c, bson.M{"$and": []bson.M{{"tags": tag}, visibles}})
+}
+
+// VerTendencias devuelve los tags en tendencia en la ventana ?ventana= (1h, 24h, 7d).
+// En lugar de contar usos se detectan picos: se compara cuántos usuarios distintos
+// usaron el tag en la ventana con lo esperado según la línea base anterior, y se
+// ordena por la desviación (actual - esperado) / sqrt(esperado + 1).
+func VerTendencias(c echo.Context) error {
+	nombre := c.QueryParam("ventana")
+	if nombre == "" {
+		nombre = "24h"
+	}
+	ventana, ok := ventanasTendencias[nombre]
+	if !ok {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Ventana no válida (1h, 24h, 7d)"})
+	}
+	limite := utils.ParsearLimite(c.QueryParam("limit"), 10, 50)
+
+	ahora := time.Now()
+	inicioActual := ahora.Add(-ventana.actual)
+	inicioBase := inicioActual.Add(-ventana.base)
+	enActual := bson.M{"$gte": bson.A{"$fecha", inicioActual}}
+
+	cur, err := config.GetCollection("tag_usos").Aggregate(context.TODO(), mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"fecha": bson.M{"$gte": inicioBase}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":  "$tag",
+			"usos": bson.M{"$sum": bson.M{"$cond": bson.A{enActual, 1, 0}}},
+			"usuariosActual": bson.M{"$addToSet": bson.M{
+				"$cond": bson.A{enActual, "$usuarioId", "$$REMOVE"},
+			}},
+			"usuariosBase": bson.M{"$addToSet": bson.M{
+				"$cond": bson.A{enActual, "$$REMOVE", "$usuarioId"},
+			}},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"usos":         1,
+			"usuarios":     bson.M{"$size": "$usuariosActual"},
+			"usuariosBase": bson.M{"$size": "$usuariosBase"},
+		}}},
+		{{Key: "$match", Value: bson.M{"usuarios": bson.M{"$gte": minimoUsuariosTendencia}}}},
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al calcular tendencias"})
+	}
+	var filas []struct {
+		Tag          string `bson:"_id"`
+		Usos         int    `bson:"usos"`
+		Usuarios     int    `bson:"usuarios"`
+		UsuariosBase int    `bson:"usuariosBase"`
+	}
+	if err := cur.All(context.TODO(), &filas); err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al leer tendencias"})
+	}
+
+	proporcion := float64(ventana.actual) / float64(ventana.base)
+	tendencias := []models.Tendencia{}
+	for _, f := range filas {
+		esperado := float64(f.UsuariosBase) * proporcion
+		puntaje := (float64(f.Usuarios) - esperado) / math.Sqrt(esperado+1)
+		if puntaje <= 0 {
+			continue
+		}
+		tendencias = append(tendencias, models.Tendencia{
+			Tag:      f.Tag,
+			Usos:     f.Usos,
+			Usuarios: f.Usuarios,
+			Esperado: math.Round(esperado*100) / 100,
+			Puntaje:  math.Round(puntaje*100) / 100,
+		})
+	}
+	sort.Slice(tendencias, func(i, j int) bool { return tendencias[i].Puntaje > tendencias[j].Puntaje })
+	if len(tendencias) > limite {
+		tendencias = tendencias[:limite]
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"ventana": nombre, "data": tendencias})
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"post-service/config"
+	"post-service/models"
+	"post-service/utils"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// audiencia son los campos de un post que deciden quién puede verlo.
+type audiencia struct {
+	visibilidad string
+	grupoID     *primitive.ObjectID
+	institucion string
+}
+
+// aplicar copia la audiencia en el post.
+func (a audiencia) aplicar(post *models.Post) {
+	post.Visibilidad = a.visibilidad
+	post.GrupoID = a.grupoID
+	post.Institucion = a.institucion
+}
+
+// CambiarVisibilidad cambia quién puede ver el post :id ({autorId, visibilidad,
+// grupoId}). No cuenta como edición ni genera una revisión.
+func CambiarVisibilidad(c echo.Context) error {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID del post inválido"})
+	}
+	var body struct {
+		AutorID     string `json:"autorId"`
+		Visibilidad string `json:"visibilidad"`
+		GrupoID     string `json:"grupoId"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Error al parsear el cuerpo JSON"})
+	}
+	autorID, err := primitive.ObjectIDFromHex(body.AutorID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "ID de autor inválido"})
+	}
+	if body.Visibilidad == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Falta la visibilidad"})
+	}
+	nueva, ok := audienciaPedida(c, autorID, body.Visibilidad, body.GrupoID)
+	if !ok {
+		return nil
+	}
+
+	set, quitar := bson.M{"visibilidad": nueva.visibilidad}, bson.M{}
+	if nueva.grupoID != nil {
+		set["grupoId"] = *nueva.grupoID
+	} else {
+		quitar["grupoId"] = ""
+	}
+	if nueva.institucion != "" {
+		set["institucion"] = nueva.institucion
+	} else {
+		quitar["institucion"] = ""
+	}
+	actualizacion := bson.M{"$set": set}
+	if len(quitar) > 0 {
+		actualizacion["$unset"] = quitar
+	}
+
+	var post models.Post
+	err = config.GetCollection("posts").FindOneAndUpdate(context.TODO(),
+		bson.M{"_id": postID, "autorId": autorID, "repostDe": nil},
+		actualizacion,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&post)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "Post no encontrado"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al cambiar la visibilidad"})
+	}
+	return c.JSON(http.StatusOK, post)
+}
+
+// audienciaPedida valida la visibilidad (y el grupo, si corresponde) que autorID
+// pide para un post; vacía equivale a public. Si no es válida responde al cliente
+// y devuelve ok = false.
+func audienciaPedida(c echo.Context, autorID primitive.ObjectID, visibilidad, grupo string) (audiencia, bool) {
+	if visibilidad == "" {
+		visibilidad = models.VisibilidadPublica
+	}
+	if !utils.EsVisibilidad(visibilidad) {
+		c.JSON(http.StatusBadRequest, echo.Map{"message": "Visibilidad no válida (public, followers, institution, group, private)"})
+		return audiencia{}, false
+	}
+	a := audiencia{visibilidad: visibilidad}
+
+	switch visibilidad {
+	case models.VisibilidadGrupo:
+		grupoID, err := primitive.ObjectIDFromHex(grupo)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, echo.Map{"message": "Se requiere un grupoId válido"})
+			return audiencia{}, false
+		}
+		n, err := config.GetCollection("grupos").CountDocuments(context.TODO(), bson.M{"_id": grupoID, "duenoId": autorID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar el grupo"})
+			return audiencia{}, false
+		}
+		if n == 0 {
+			c.JSON(http.StatusNotFound, echo.Map{"message": "Grupo no encontrado"})
+			return audiencia{}, false
+		}
+		a.grupoID = &grupoID
+	case models.VisibilidadInstitucion:
+		institucion, err := utils.InstitucionDe(autorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al buscar la institución"})
+			return audiencia{}, false
+		}
+		if institucion == "" {
+			c.JSON(http.StatusBadRequest, echo.Map{"message": "Tu código institucional no indica la institución: debe ser un correo institucional"})
+			return audiencia{}, false
+		}
+		a.institucion = institucion
+	}
+	return a, true
+}
+
+// postVisible verifica que viewerID pueda ver el post postID. Si no puede (o no
+// existe) responde 404, sin revelar cuál de las dos cosas pasó, y devuelve false.
+func postVisible(c echo.Context, viewerID, postID primitive.ObjectID) bool {
+	visible, err := utils.PuedeVerPost(viewerID, postID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error al verificar la visibilidad"})
+		return false
+	}
+	if !visible {
+		c.JSON(http.StatusNotFound, echo.Map{"message": "Post no encontrado"})
+		return false
+	}
+	return true
+}
@@ -4,6 +4,8 @@ import (
 	"log"
 	"post-service/config"
 	"post-service/routes"
+	"post-service/storage"
+	"post-service/utils"
 
 	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
@@ -18,6 +20,14 @@ func main() {
 
 	// Conectar a la base de datos
 	config.ConnectDB()
+	storage.Conectar()
+	config.CrearIndices()
+	utils.MigrarLikes()
+	utils.MigrarTags()
+	utils.InicializarContadores()
+	utils.IniciarWorkers()
+	utils.IniciarLimpieza()
+	utils.IniciarPublicador()
 
 	// Inicializar Echo
 	e := echo.New()
@@ -25,6 +35,8 @@ func main() {
 	// Cargar rutas
 	routes.PostRoutes(e)
 	routes.UserRoutes(e)
+	routes.TagRoutes(e)
+	routes.MediaRoutes(e)
 	routes.WebSocketRoutes(e)
 
 	// Arrancar servidor
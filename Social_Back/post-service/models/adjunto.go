@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Adjunto es un archivo subido a través de POST /media o de una URL prefirmada
+// (colección "adjuntos").
+// El archivo se guarda con un nombre derivado de su contenido (Hash), así que
+// dos subidas idénticas comparten el mismo archivo en disco.
+type Adjunto struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Hash      string             `bson:"hash" json:"hash"`                       // sha256 del contenido
+	Archivo   string             `bson:"archivo" json:"-"`                       // nombre en el almacenamiento: <hash><ext>
+	Nombre    string             `bson:"nombre" json:"nombre"`                   // nombre original, solo para mostrar
+	MIME      string             `bson:"mime" json:"mime"`                       // detectado a partir del contenido
+	Tipo      string             `bson:"tipo" json:"tipo"`                       // imagen, video, documento
+	Tamano    int64              `bson:"tamano" json:"tamano"`                   // en bytes
+	Ancho     int                `bson:"ancho,omitempty" json:"ancho,omitempty"` // solo imágenes
+	Alto      int                `bson:"alto,omitempty" json:"alto,omitempty"`
+	URL       string             `bson:"url" json:"url"`
+	SubidoPor primitive.ObjectID `bson:"subidoPor" json:"subidoPor"`
+	Estado    string             `bson:"estado,omitempty" json:"estado,omitempty"` // "pendiente" hasta confirmar una subida directa
+	Fecha     time.Time          `bson:"fecha" json:"fecha"`
+
+	// Resultado del procesamiento en segundo plano
+	Procesamiento string     `bson:"procesamiento,omitempty" json:"procesamiento,omitempty"` // processing, ready, failed
+	Variantes     []Variante `bson:"variantes,omitempty" json:"variantes,omitempty"`
+	SrcsetWebP    string     `bson:"srcsetWebp,omitempty" json:"srcsetWebp,omitempty"` // listo para <source srcset>
+	SrcsetJPEG    string     `bson:"srcsetJpeg,omitempty" json:"srcsetJpeg,omitempty"`
+	Blurhash      string     `bson:"blurhash,omitempty" json:"blurhash,omitempty"` // vista previa mientras carga
+	Duracion      float64    `bson:"duracion,omitempty" json:"duracion,omitempty"` // videos, en segundos
+	Codec         string     `bson:"codec,omitempty" json:"codec,omitempty"`       // códec de video original
+	Poster        string     `bson:"poster,omitempty" json:"poster,omitempty"`     // URL de la portada: un fotograma o la primera página
+	URLWeb        string     `bson:"urlWeb,omitempty" json:"urlWeb,omitempty"`     // MP4 H.264/AAC apto para navegadores
+	HLS           string     `bson:"hls,omitempty" json:"hls,omitempty"`           // URL de la playlist maestra, si se generó
+	Paginas       int        `bson:"paginas,omitempty" json:"paginas,omitempty"`   // documentos: páginas o diapositivas
+	Texto         string     `bson:"texto,omitempty" json:"-"`                     // texto extraído del documento, para la búsqueda
+	Derivados     []string   `bson:"derivados,omitempty" json:"-"`                 // archivos generados al procesarlo, para poder borrarlos
+}
+
+// Estados de Adjunto.Procesamiento.
+const (
+	ProcesamientoEnCurso = "processing"
+	ProcesamientoListo   = "ready"
+	ProcesamientoFallido = "failed"
+)
+
+// Variante es una versión redimensionada de una imagen, sin metadatos y con la
+// orientación EXIF ya aplicada.
+type Variante struct {
+	Ancho   int    `bson:"ancho" json:"ancho"`
+	Alto    int    `bson:"alto" json:"alto"`
+	Formato string `bson:"formato" json:"formato"` // webp, jpeg
+	URL     string `bson:"url" json:"url"`
+	Tamano  int64  `bson:"tamano" json:"tamano"`
+}
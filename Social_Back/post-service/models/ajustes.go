@@ -0,0 +1,13 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// AjustesUsuario guarda las preferencias sociales de un usuario dentro del post-service.
+// El _id coincide con el ID del usuario en la colección "users".
+type AjustesUsuario struct {
+	UsuarioID     primitive.ObjectID `bson:"_id" json:"usuarioId"`
+	CuentaPrivada bool               `bson:"cuentaPrivada" json:"cuentaPrivada"`
+	// TimelineMaterializado indica que su feed se sirve desde la colección timelines
+	// (fan-out-on-write) porque sigue a demasiadas cuentas para leerlo al vuelo.
+	TimelineMaterializado bool `bson:"timelineMaterializado,omitempty" json:"-"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Bloqueo impide cualquier interacción entre dos usuarios en ambos sentidos:
+// follows, comentarios, likes, notificaciones y visibilidad en listados.
+type Bloqueo struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	BloqueadorID primitive.ObjectID `bson:"bloqueadorId" json:"bloqueadorId"`
+	BloqueadoID  primitive.ObjectID `bson:"bloqueadoId" json:"bloqueadoId"`
+	Fecha        time.Time          `bson:"fecha" json:"fecha"`
+}
+
+// Silencio oculta el contenido de un usuario en los listados de quien lo silenció,
+// sin que el silenciado lo sepa ni cambie nada para él.
+type Silencio struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UsuarioID    primitive.ObjectID `bson:"usuarioId" json:"usuarioId"`
+	SilenciadoID primitive.ObjectID `bson:"silenciadoId" json:"silenciadoId"`
+	Fecha        time.Time          `bson:"fecha" json:"fecha"`
+}
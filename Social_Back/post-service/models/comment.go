@@ -6,11 +6,36 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// Comentario representa un comentario hecho en un post.
+// Comentario representa un comentario hecho en un post. Las respuestas guardan el
+// comentario al que responden en ParentID; los comentarios de primer nivel no lo tienen.
 type Comentario struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	PostID    primitive.ObjectID `bson:"postId,omitempty" json:"postId"`
-	AutorID   primitive.ObjectID `bson:"autorId,omitempty" json:"autorId"`
-	Contenido string             `bson:"contenido,omitempty" json:"contenido"`
-	Fecha     time.Time          `bson:"fecha,omitempty" json:"fecha"`
+	ID              primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	PostID          primitive.ObjectID  `bson:"postId,omitempty" json:"postId"`
+	AutorID         primitive.ObjectID  `bson:"autorId,omitempty" json:"autorId"`
+	ParentID        *primitive.ObjectID `bson:"parentId,omitempty" json:"parentId,omitempty"`
+	Profundidad     int                 `bson:"profundidad" json:"profundidad"` // 0 en los de primer nivel
+	Contenido       string              `bson:"contenido,omitempty" json:"contenido"`
+	Menciones       []Mencion           `bson:"menciones,omitempty" json:"menciones,omitempty"`
+	TotalRespuestas int64               `bson:"totalRespuestas" json:"totalRespuestas"`
+	TotalLikes      int64               `bson:"totalLikes" json:"totalLikes"` // total de reacciones de cualquier tipo
+	Reacciones      map[string]int64    `bson:"reacciones,omitempty" json:"reacciones"`
+	Editado         bool                `bson:"editado,omitempty" json:"editado"`
+	FechaEdicion    *time.Time          `bson:"fechaEdicion,omitempty" json:"fechaEdicion,omitempty"`
+	// Un comentario eliminado que tiene respuestas se conserva vacío para no romper el hilo
+	Eliminado    bool      `bson:"eliminado,omitempty" json:"eliminado"`
+	EliminadoPor string    `bson:"eliminadoPor,omitempty" json:"eliminadoPor,omitempty"` // autor, autorPost o moderacion
+	Fecha        time.Time `bson:"fecha,omitempty" json:"fecha"`
+	Idioma       string    `bson:"idioma,omitempty" json:"-"` // spanish/english, para el índice de texto
+}
+
+// ComentarioDetalle es un comentario listado con el perfil público de su autor y la
+// reacción de quien consulta. Username y Text repiten autor.username y contenido
+// con los nombres que usa el frontend.
+type ComentarioDetalle struct {
+	Comentario `bson:",inline"`
+	Autor      *UsuarioPublico `bson:"autor,omitempty" json:"autor"`
+	LeGusta    bool            `bson:"leGusta" json:"leGusta"`
+	Reaccion   string          `bson:"reaccion,omitempty" json:"reaccion,omitempty"`
+	Username   string          `bson:"-" json:"username"`
+	Text       string          `bson:"-" json:"text"`
 }
@@ -12,3 +12,12 @@ type Follow struct {
 	SeguidoID  primitive.ObjectID `bson:"seguidoId" json:"seguidoId"`
 	Fecha      time.Time          `bson:"fecha" json:"fecha"`
 }
+
+// FollowDetalle es una fila de los listados de seguidores/seguidos con el perfil
+// público del otro usuario y su relación con quien consulta.
+type FollowDetalle struct {
+	Follow  `bson:",inline"`
+	Usuario *UsuarioPublico `bson:"usuario,omitempty" json:"usuario"`
+	LoSigo  bool            `bson:"loSigo" json:"loSigo"`   // quien consulta sigue a este usuario
+	MeSigue bool            `bson:"meSigue" json:"meSigue"` // este usuario sigue a quien consulta
+}
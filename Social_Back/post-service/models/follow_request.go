@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FollowRequest es una solicitud pendiente para seguir a una cuenta privada.
+// Al aceptarse se convierte en un Follow y se elimina.
+type FollowRequest struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	SeguidorID primitive.ObjectID `bson:"seguidorId" json:"seguidorId"` // Quien solicita
+	SeguidoID  primitive.ObjectID `bson:"seguidoId" json:"seguidoId"`   // Cuenta privada
+	Fecha      time.Time          `bson:"fecha" json:"fecha"`
+}
+
+// FollowRequestDetalle es una solicitud con el perfil público de quien la envió.
+type FollowRequestDetalle struct {
+	FollowRequest `bson:",inline"`
+	Usuario       *UsuarioPublico `bson:"usuario,omitempty" json:"usuario"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Grupo es una lista de usuarios armada por su dueño para publicar posts que solo
+// ellos pueden ver (visibilidad group). Los miembros no ven el grupo ni saben
+// quién más está en él.
+type Grupo struct {
+	ID       primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	DuenoID  primitive.ObjectID   `bson:"duenoId" json:"duenoId"`
+	Nombre   string               `bson:"nombre" json:"nombre"`
+	Miembros []primitive.ObjectID `bson:"miembros" json:"miembros"`
+	Fecha    time.Time            `bson:"fecha" json:"fecha"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Guardado es un post que un usuario guardó para más tarde (colección
+// "guardados"). Puede estar además en varias de sus colecciones privadas.
+type Guardado struct {
+	ID          primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	UsuarioID   primitive.ObjectID   `bson:"usuarioId" json:"usuarioId"`
+	PostID      primitive.ObjectID   `bson:"postId" json:"postId"`
+	Colecciones []primitive.ObjectID `bson:"colecciones" json:"colecciones"`
+	Fecha       time.Time            `bson:"fecha" json:"fecha"`
+}
+
+// ColeccionGuardados es una carpeta con nombre para organizar los posts guardados.
+// Solo la ve su dueño.
+type ColeccionGuardados struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UsuarioID primitive.ObjectID `bson:"usuarioId" json:"usuarioId"`
+	Nombre    string             `bson:"nombre" json:"nombre"`
+	Fecha     time.Time          `bson:"fecha" json:"fecha"`
+	Total     int64              `bson:"-" json:"total"` // posts guardados en ella
+}
+
+// PostGuardado es un post del listado GET /users/:id/saved con los datos de
+// cuándo y dónde se guardó.
+type PostGuardado struct {
+	Post        `bson:",inline"`
+	GuardadoID  primitive.ObjectID   `bson:"guardadoId" json:"-"`
+	GuardadoEn  time.Time            `bson:"guardadoEn" json:"guardadoEn"`
+	Colecciones []primitive.ObjectID `bson:"colecciones" json:"colecciones"`
+}
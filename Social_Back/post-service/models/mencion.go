@@ -0,0 +1,12 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Mencion es un @username de un post o comentario resuelto contra "users".
+// Inicio y Fin son posiciones en runas dentro del texto e incluyen la @.
+type Mencion struct {
+	UsuarioID primitive.ObjectID `bson:"usuarioId" json:"usuarioId"`
+	Username  string             `bson:"username" json:"username"`
+	Inicio    int                `bson:"inicio" json:"inicio"`
+	Fin       int                `bson:"fin" json:"fin"`
+}
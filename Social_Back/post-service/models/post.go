@@ -7,13 +7,107 @@ import (
 )
 
 type Post struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Titulo      string             `bson:"titulo" json:"titulo"`
-	Contenido   string             `bson:"contenido" json:"contenido"`
-	Tipo        string             `bson:"tipo" json:"tipo"` // video, tutorial, documento
-	Categoria   string             `bson:"categoria" json:"categoria"`
-	Tags        []string           `bson:"tags" json:"tags"`
-	URLArchivo  string             `bson:"urlArchivo,omitempty" json:"urlArchivo,omitempty"` // si se sube
-	AutorID     primitive.ObjectID `bson:"autorId" json:"autorId"`
-	FechaCreado time.Time          `bson:"fechaCreado" json:"fechaCreado"`
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Titulo           string             `bson:"titulo" json:"titulo"`
+	Contenido        string             `bson:"contenido" json:"contenido"`
+	Tipo             string             `bson:"tipo" json:"tipo"` // video, tutorial, documento
+	Categoria        string             `bson:"categoria" json:"categoria"`
+	Tags             []string           `bson:"tags" json:"tags"`
+	Menciones        []Mencion          `bson:"menciones,omitempty" json:"menciones,omitempty"`
+	URLArchivo       string             `bson:"urlArchivo,omitempty" json:"urlArchivo,omitempty"` // URL del primer adjunto
+	Adjuntos         []Adjunto          `bson:"adjuntos,omitempty" json:"adjuntos,omitempty"`
+	Procesamiento    string             `bson:"procesamiento,omitempty" json:"procesamiento,omitempty"` // processing, ready, failed: estado conjunto de los adjuntos
+	AutorID          primitive.ObjectID `bson:"autorId" json:"autorId"`
+	FechaCreado      time.Time          `bson:"fechaCreado" json:"fechaCreado"`
+	Idioma           string             `bson:"idioma,omitempty" json:"-"`    // spanish/english, para el índice de texto
+	TotalLikes       int64              `bson:"totalLikes" json:"totalLikes"` // total de reacciones de cualquier tipo
+	Reacciones       map[string]int64   `bson:"reacciones,omitempty" json:"reacciones"`
+	TotalComentarios int64              `bson:"totalComentarios" json:"totalComentarios"`
+	Editado          bool               `bson:"editado,omitempty" json:"editado"`
+	FechaEdicion     *time.Time         `bson:"fechaEdicion,omitempty" json:"fechaEdicion,omitempty"`
+	// Si es true no se aceptan comentarios nuevos
+	ComentariosBloqueados bool `bson:"comentariosBloqueados,omitempty" json:"comentariosBloqueados"`
+	// Si quien consulta (?viewerId=) guardó el post; no se almacena
+	Guardado bool `bson:"-" json:"saved"`
+	// Un repost no tiene contenido propio: comparte el post RepostDe. Una cita es
+	// un post normal que además incluye el post CitaDe.
+	RepostDe     *primitive.ObjectID `bson:"repostDe,omitempty" json:"repostDe,omitempty"`
+	CitaDe       *primitive.ObjectID `bson:"citaDe,omitempty" json:"citaDe,omitempty"`
+	TotalReposts int64               `bson:"totalReposts,omitempty" json:"totalReposts"`
+	TotalCitas   int64               `bson:"totalCitas,omitempty" json:"totalCitas"`
+	// Post compartido o citado, tal como lo ve quien consulta; no se almacena
+	Original *Post `bson:"-" json:"original,omitempty"`
+	// Quién puede ver el post además de su autor (vacío en posts anteriores = public)
+	Visibilidad string              `bson:"visibilidad,omitempty" json:"visibilidad"`
+	GrupoID     *primitive.ObjectID `bson:"grupoId,omitempty" json:"grupoId,omitempty"` // con visibilidad group
+	Institucion string              `bson:"institucion,omitempty" json:"-"`             // del autor al publicar, con visibilidad institution
+	// draft, scheduled o published (vacío en posts anteriores = published). Un post
+	// programado se publica en PublicarEn; hasta entonces solo lo ve su autor
+	Estado     string     `bson:"estado,omitempty" json:"status"`
+	PublicarEn *time.Time `bson:"publicarEn,omitempty" json:"publishAt,omitempty"`
+	// Desde cuándo se están enviando sus notificaciones y copias a timelines tras
+	// publicarse; se borra al terminar (ver utils.Difundir)
+	Difundiendo *time.Time `bson:"difundiendo,omitempty" json:"-"`
+}
+
+// Estados de publicación de un post.
+const (
+	EstadoBorrador   = "draft"     // solo lo ve el autor hasta que lo publique
+	EstadoProgramado = "scheduled" // se publica solo en PublicarEn
+	EstadoPublicado  = "published"
+)
+
+// Niveles de visibilidad de un post (ver utils.FiltroVisibles).
+const (
+	VisibilidadPublica     = "public"      // cualquiera, salvo que la cuenta sea privada
+	VisibilidadSeguidores  = "followers"   // solo quienes siguen al autor
+	VisibilidadInstitucion = "institution" // solo usuarios de la misma institución
+	VisibilidadGrupo       = "group"       // solo los miembros de un grupo del autor
+	VisibilidadPrivada     = "private"     // solo el autor
+)
+
+// PostDetalle es la respuesta de GET /posts/:id: el post con su autor y la
+// reacción de quien consulta.
+type PostDetalle struct {
+	Post
+	Autor    *UsuarioPublico `json:"autor"`
+	LeGusta  bool            `json:"leGusta"`            // si quien consulta reaccionó
+	Reaccion string          `json:"reaccion,omitempty"` // tipo de esa reacción
+}
+
+// PostRevision guarda cómo era un post antes de una edición.
+type PostRevision struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PostID    primitive.ObjectID `bson:"postId" json:"postId"`
+	Titulo    string             `bson:"titulo" json:"titulo"`
+	Contenido string             `bson:"contenido" json:"contenido"`
+	Categoria string             `bson:"categoria" json:"categoria"`
+	Tags      []string           `bson:"tags" json:"tags"`
+	Fecha     time.Time          `bson:"fecha" json:"fecha"` // Momento en que se reemplazó esta versión
+}
+
+// ResultadoBusqueda es un post encontrado por GET /search con su relevancia y los
+// fragmentos donde aparecen los términos buscados.
+type ResultadoBusqueda struct {
+	Post
+	Puntaje    float64              `json:"puntaje"`
+	Resaltados FragmentosResaltados `json:"resaltados"`
+}
+
+// FragmentosResaltados contiene extractos con las coincidencias marcadas con <mark>.
+// Son HTML ya escapado: <mark> es la única etiqueta que puede aparecer.
+type FragmentosResaltados struct {
+	Titulo     string   `json:"titulo,omitempty"`
+	Contenido  string   `json:"contenido,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Comentario string   `json:"comentario,omitempty"`
+	Documento  string   `json:"documento,omitempty"` // texto de un documento adjunto
+}
+
+// PostEliminado es un post en la papelera (colección "posts_papelera"). Se puede
+// restaurar hasta que vence el plazo y después se purga junto con todo lo que
+// depende de él.
+type PostEliminado struct {
+	Post        `bson:",inline"`
+	EliminadoEn time.Time `bson:"eliminadoEn" json:"eliminadoEn"`
 }
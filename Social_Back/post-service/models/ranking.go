@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PostRankeado es un post del feed "para ti" con su puntaje final.
+// Desglose solo se incluye en modo debug (?debug=true) para ajustar los pesos.
+type PostRankeado struct {
+	Post
+	Puntaje  float64          `json:"puntaje"`
+	Desglose *DesglosePuntaje `json:"desglose,omitempty"`
+}
+
+// DesglosePuntaje detalla cuánto aportó cada señal al puntaje de un post.
+type DesglosePuntaje struct {
+	Recencia   float64 `json:"recencia"`   // decaimiento exponencial por antigüedad
+	Velocidad  float64 `json:"velocidad"`  // likes y comentarios por hora
+	Afinidad   float64 `json:"afinidad"`   // interacciones previas con el autor
+	Intereses  float64 `json:"intereses"`  // coincidencia de tags/categoría con el historial
+	Base       float64 `json:"base"`       // suma ponderada de las señales anteriores
+	Diversidad float64 `json:"diversidad"` // factor aplicado por posts previos del mismo autor
+}
+
+// RankingParaTi es el orden del feed "para ti" calculado para la primera página.
+// Las páginas siguientes lo recorren en lugar de recalcularlo, para no repetir ni
+// saltear posts cuando cambian los puntajes. Se borra solo al vencer (ver índice).
+type RankingParaTi struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	UsuarioID primitive.ObjectID `bson:"usuarioId"`
+	Posts     []EntradaRanking   `bson:"posts"`
+	Fecha     time.Time          `bson:"fecha"`
+}
+
+// EntradaRanking es un post del ranking con el puntaje que tenía al calcularlo.
+type EntradaRanking struct {
+	PostID   primitive.ObjectID `bson:"postId"`
+	Puntaje  float64            `bson:"puntaje"`
+	Desglose DesglosePuntaje    `bson:"desglose"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reaccion es la reacción (like, love...) de un usuario a un post o, si
+// ComentarioID no es nulo, a un comentario de ese post. Cada usuario tiene como
+// máximo una reacción por post o comentario.
+type Reaccion struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	PostID       primitive.ObjectID  `bson:"postId" json:"postId"`
+	ComentarioID *primitive.ObjectID `bson:"comentarioId" json:"comentarioId,omitempty"`
+	UsuarioID    primitive.ObjectID  `bson:"usuarioId" json:"usuarioId"`
+	Tipo         string              `bson:"tipo" json:"tipo"`
+	Fecha        time.Time           `bson:"fecha" json:"fecha"`
+}
+
+// ReaccionDetalle es una reacción con el perfil público de quien reaccionó.
+type ReaccionDetalle struct {
+	Reaccion `bson:",inline"`
+	Usuario  *UsuarioPublico `bson:"usuario,omitempty" json:"usuario"`
+}
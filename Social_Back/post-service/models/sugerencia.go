@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Sugerencia es un usuario recomendado para seguir junto con el motivo que se muestra.
+type Sugerencia struct {
+	Usuario          UsuarioPublico `json:"usuario"`
+	Puntaje          float64        `json:"puntaje"`
+	Motivo           string         `json:"motivo"` // Ej: "Seguido por 3 personas que sigues"
+	SeguidosEnComun  int            `json:"seguidosEnComun"`
+	MismaInstitucion bool           `json:"mismaInstitucion"`
+	MismaCohorte     bool           `json:"mismaCohorte"`
+	TagsEnComun      []string       `json:"tagsEnComun,omitempty"`
+}
+
+// SugerenciaDescartada evita que un usuario vuelva a aparecer en las sugerencias de otro.
+type SugerenciaDescartada struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UsuarioID    primitive.ObjectID `bson:"usuarioId" json:"usuarioId"`
+	DescartadoID primitive.ObjectID `bson:"descartadoId" json:"descartadoId"`
+	Fecha        time.Time          `bson:"fecha" json:"fecha"`
+}
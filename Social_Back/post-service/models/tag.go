@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UsoTag registra cada vez que un tag aparece en un post o comentario nuevo.
+// Se usa para calcular tendencias y expira automáticamente (índice TTL).
+type UsoTag struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Tag       string             `bson:"tag" json:"tag"`
+	PostID    primitive.ObjectID `bson:"postId" json:"postId"`
+	UsuarioID primitive.ObjectID `bson:"usuarioId" json:"usuarioId"`
+	Origen    string             `bson:"origen" json:"origen"` // "post" o "comentario"
+	Fecha     time.Time          `bson:"fecha" json:"fecha"`
+}
+
+// Tendencia es un tag cuyo uso en la ventana actual supera lo esperado según su
+// historial reciente.
+type Tendencia struct {
+	Tag      string  `json:"tag"`
+	Usos     int     `json:"usos"`     // apariciones en la ventana actual
+	Usuarios int     `json:"usuarios"` // usuarios distintos que lo usaron en la ventana
+	Esperado float64 `json:"esperado"` // usuarios esperados según la línea base
+	Puntaje  float64 `json:"puntaje"`  // cuánto se desvía del valor esperado
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TimelineEntrada es una copia ligera de un post en el timeline precalculado
+// (fan-out-on-write) de un usuario que sigue a muchas cuentas.
+type TimelineEntrada struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UsuarioID   primitive.ObjectID `bson:"usuarioId" json:"usuarioId"` // Dueño del timeline
+	PostID      primitive.ObjectID `bson:"postId" json:"postId"`
+	AutorID     primitive.ObjectID `bson:"autorId" json:"autorId"`
+	FechaCreado time.Time          `bson:"fechaCreado" json:"fechaCreado"` // Fecha del post, para ordenar
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TrabajoMedia es una tarea de procesamiento en segundo plano de un adjunto
+// (colección "trabajos_media").
+type TrabajoMedia struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Tipo      string             `bson:"tipo" json:"tipo"` // imagen, video
+	AdjuntoID primitive.ObjectID `bson:"adjuntoId" json:"adjuntoId"`
+	Estado    string             `bson:"estado" json:"estado"` // pendiente, procesando, listo, fallido
+	Intentos  int                `bson:"intentos" json:"intentos"`
+	Error     string             `bson:"error,omitempty" json:"error,omitempty"`
+	Fecha     time.Time          `bson:"fecha" json:"fecha"`
+	Inicio    *time.Time         `bson:"inicio,omitempty" json:"inicio,omitempty"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// UsuarioPublico es la vista pública de un documento de la colección "users".
+// Solo incluye los campos que cualquier otro usuario puede ver.
+type UsuarioPublico struct {
+	ID       primitive.ObjectID `bson:"_id" json:"id"`
+	Username string             `bson:"username" json:"username"`
+	Rol      string             `bson:"rol,omitempty" json:"rol,omitempty"`
+	// URL de la imagen de perfil, si el usuario subió una
+	FotoPerfil string `bson:"profilePicture,omitempty" json:"profilePicture,omitempty"`
+}
@@ -11,6 +11,36 @@ func UserRoutes(e *echo.Echo) {
 	e.DELETE("/users/:id/unfollow", controllers.DejarDeSeguir)
 	e.GET("/users/:id/following", controllers.VerSeguidos)
 	e.GET("/users/:id/followers", controllers.VerSeguidores)
+	e.DELETE("/users/:id/follow-request", controllers.CancelarSolicitud)
+	e.GET("/users/:id/follow-requests", controllers.VerSolicitudesSeguimiento)
+	e.POST("/users/:id/follow-requests/:requestId/accept", controllers.AceptarSolicitud)
+	e.POST("/users/:id/follow-requests/:requestId/reject", controllers.RechazarSolicitud)
+	e.GET("/users/:id/privacidad", controllers.ObtenerPrivacidad)
+	e.PATCH("/users/:id/privacidad", controllers.ActualizarPrivacidad)
+	e.POST("/users/:id/block", controllers.BloquearUsuario)
+	e.DELETE("/users/:id/unblock", controllers.DesbloquearUsuario)
+	e.GET("/users/:id/blocked", controllers.VerBloqueados)
+	e.POST("/users/:id/mute", controllers.SilenciarUsuario)
+	e.DELETE("/users/:id/unmute", controllers.DejarDeSilenciar)
+	e.GET("/users/:id/muted", controllers.VerSilenciados)
+	e.GET("/users/:id/mentions/autocomplete", controllers.AutocompletarMenciones) // ?q=prefijo
+	e.GET("/users/:id/suggestions", controllers.VerSugerencias)
+	e.POST("/users/:id/suggestions/:candidatoId/dismiss", controllers.DescartarSugerencia)
+	e.GET("/users/:id/saved", controllers.VerGuardados)   // ?coleccion=
+	e.GET("/users/:id/drafts", controllers.VerBorradores) // Borradores y programados (?viewerId= del autor)
+	e.GET("/users/:id/collections", controllers.VerColecciones)
+	e.POST("/users/:id/collections", controllers.CrearColeccion)
+	e.PATCH("/users/:id/collections/:coleccionId", controllers.RenombrarColeccion)
+	e.DELETE("/users/:id/collections/:coleccionId", controllers.EliminarColeccion)
+	e.PUT("/users/:id/collections/:coleccionId/posts/:postId", controllers.AgregarAColeccion)
+	e.DELETE("/users/:id/collections/:coleccionId/posts/:postId", controllers.QuitarDeColeccion)
+	// Grupos para publicar posts con visibilidad group; solo los ve su dueño
+	e.GET("/users/:id/groups", controllers.VerGrupos)
+	e.POST("/users/:id/groups", controllers.CrearGrupo) // {nombre, miembros}
+	e.PATCH("/users/:id/groups/:grupoId", controllers.RenombrarGrupo)
+	e.DELETE("/users/:id/groups/:grupoId", controllers.EliminarGrupo)
+	e.PUT("/users/:id/groups/:grupoId/members/:usuarioId", controllers.AgregarMiembro)
+	e.DELETE("/users/:id/groups/:grupoId/members/:usuarioId", controllers.QuitarMiembro)
 	e.GET("/users/:id/notificaciones", controllers.VerNotificaciones)
 	e.PATCH("/users/:id/notificaciones/:notiId/leida", controllers.MarcarNotificacionLeida)
 	e.DELETE("/users/:id/notificaciones/:notiId", controllers.EliminarNotificacion)
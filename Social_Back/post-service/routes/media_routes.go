@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"post-service/controllers"
+
+	"github.com/labstack/echo/v4"
+)
+
+func MediaRoutes(e *echo.Echo) {
+	e.POST("/media", controllers.SubirMedia) // multipart: archivo, usuarioId
+	e.POST("/media/presign", controllers.PresignarSubida)
+	e.POST("/media/:id/confirm", controllers.ConfirmarSubida)
+	e.GET("/media/:archivo", controllers.ServirMedia) // <sha256>.<ext>
+}
@@ -13,14 +13,42 @@ func PostRoutes(e *echo.Echo) {
 	})
 
 	// CRUD de posts
-	e.POST("/posts", controllers.CrearPost)   // Crear post
-	e.GET("/posts", controllers.ObtenerPosts) // Obtener todos (con filtros)
-	// e.GET("/posts/:id", controllers.ObtenerPostPorID) // Obtener uno por ID
+	e.POST("/posts", controllers.CrearPost)                          // Crear post
+	e.GET("/posts", controllers.ObtenerPosts)                        // Obtener todos (con filtros)
+	e.GET("/posts/:id", controllers.ObtenerPostPorID)                // Obtener uno por ID
+	e.PATCH("/posts/:id", controllers.ActualizarPost)                // Editar (solo el autor)
+	e.PATCH("/posts/:id/visibility", controllers.CambiarVisibilidad) // {autorId, visibilidad, grupoId}
+	e.PATCH("/posts/:id/status", controllers.CambiarEstado)          // {autorId, status, publishAt}
+	e.GET("/posts/:id/revisions", controllers.VerRevisionesPost)
 	e.GET("/posts/usuario/:id", controllers.ObtenerPostsPorUsuario)
-	e.DELETE("/posts/:id", controllers.EliminarPost) // Eliminar post
+	e.GET("/search", controllers.Buscar)              // Búsqueda de texto completo
+	e.GET("/feed", controllers.VerFeed)               // Timeline de seguidos (?viewerId=)
+	e.GET("/feed/for-you", controllers.VerFeedParaTi) // Feed rankeado (?viewerId=&debug=true)
+	e.DELETE("/posts/:id", controllers.EliminarPost)  // Autor o moderador; a la papelera (?definitivo=true para purgarlo)
+	e.POST("/posts/:id/restore", controllers.RestaurarPost)
 	// Comentarios
 	e.POST("/posts/:id/comments", controllers.CrearComentario)
-	e.GET("/posts/:id/comments", controllers.ObtenerComentarios)
+	e.GET("/posts/:id/comments", controllers.ObtenerComentarios) // Solo primer nivel
+	e.GET("/posts/:id/comments/:commentId/replies", controllers.VerRespuestas)
+	e.PATCH("/posts/:id/comments/:commentId", controllers.EditarComentario)    // Solo el autor
+	e.DELETE("/posts/:id/comments/:commentId", controllers.EliminarComentario) // Autor, autor del post o moderador
+	e.GET("/posts/:id/comments/:commentId/reactions", controllers.VerReaccionesComentario)
+	e.PUT("/posts/:id/comments/:commentId/reactions", controllers.FijarReaccionComentario)
+	e.DELETE("/posts/:id/comments/:commentId/reactions", controllers.QuitarReaccionComentario)
+	e.POST("/posts/:id/lock-comments", controllers.BloquearComentarios)
+	e.POST("/posts/:id/unlock-comments", controllers.DesbloquearComentarios)
 	// Likes
-	e.POST("/posts/:id/like", controllers.ToggleLike)
+	e.POST("/posts/:id/like", controllers.DarLike)
+	e.POST("/posts/:id/unlike", controllers.QuitarLike)
+	// Reposts (las citas se crean con POST /posts y {citaDe})
+	e.POST("/posts/:id/repost", controllers.Repostear) // {usuarioId}
+	e.DELETE("/posts/:id/repost", controllers.DeshacerRepost)
+	// Guardados
+	e.PUT("/posts/:id/save", controllers.GuardarPost) // {usuarioId, colecciones?}
+	e.DELETE("/posts/:id/save", controllers.QuitarGuardado)
+	// Reacciones
+	e.GET("/reactions", controllers.VerTiposReaccion)
+	e.GET("/posts/:id/reactions", controllers.VerReaccionesPost) // ?tipo=&viewerId=
+	e.PUT("/posts/:id/reactions", controllers.FijarReaccionPost)
+	e.DELETE("/posts/:id/reactions", controllers.QuitarReaccionPost)
 }
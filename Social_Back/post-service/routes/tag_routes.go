@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"post-service/controllers"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TagRoutes(e *echo.Echo) {
+	e.GET("/tags/trending", controllers.VerTendencias) // ?ventana=1h|24h|7d
+	e.GET("/tags/:tag/posts", controllers.VerPostsPorTag)
+}
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Local guarda los objetos como archivos bajo un directorio.
+type Local struct {
+	dir string
+}
+
+// NuevoLocal crea un driver local que guarda los archivos bajo dir.
+func NuevoLocal(dir string) *Local {
+	return &Local{dir: dir}
+}
+
+// ruta convierte una clave en una ruta dentro de dir, rechazando claves que
+// intenten salir de él.
+func (l *Local) ruta(clave string) (string, error) {
+	limpia := filepath.Clean("/" + clave)
+	if limpia == "/" || strings.Contains(clave, "\\") {
+		return "", ErrNoExiste
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(limpia)), nil
+}
+
+// Put escribe primero en un temporal y lo renombra, para que nunca se lea un
+// archivo a medio escribir.
+func (l *Local) Put(ctx context.Context, clave string, r io.Reader, tamano int64, mime string) error {
+	ruta, err := l.ruta(clave)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(ruta), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(ruta), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), ruta)
+}
+
+// Get abre el archivo de la clave.
+func (l *Local) Get(ctx context.Context, clave string) (Objeto, error) {
+	ruta, err := l.ruta(clave)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(ruta)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoExiste
+	}
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &archivoLocal{File: f, info: info}, nil
+}
+
+// Existe indica si el archivo de la clave existe.
+func (l *Local) Existe(ctx context.Context, clave string) (bool, error) {
+	ruta, err := l.ruta(clave)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(ruta)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Copiar copia el archivo origen en destino.
+func (l *Local) Copiar(ctx context.Context, origen, destino string) error {
+	obj, err := l.Get(ctx, origen)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+	return l.Put(ctx, destino, obj, obj.Tamano(), "")
+}
+
+// Delete borra el archivo de la clave.
+func (l *Local) Delete(ctx context.Context, clave string) error {
+	ruta, err := l.ruta(clave)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(ruta); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// PresignPost no está disponible en disco local: las subidas pasan por post-service.
+func (l *Local) PresignPost(ctx context.Context, clave string, subida SubidaDirecta, expira time.Duration) (string, map[string]string, error) {
+	return "", nil, ErrNoSoportado
+}
+
+// Sha256 no está disponible en disco local: no se guarda el hash de los archivos.
+func (l *Local) Sha256(ctx context.Context, clave string) ([]byte, error) {
+	return nil, ErrNoSoportado
+}
+
+// PresignGet no está disponible en disco local: los archivos se sirven desde post-service.
+func (l *Local) PresignGet(ctx context.Context, clave string, expira time.Duration, cabeceras map[string]string) (string, error) {
+	return "", ErrNoSoportado
+}
+
+// archivoLocal adapta *os.File a Objeto.
+type archivoLocal struct {
+	*os.File
+	info os.FileInfo
+}
+
+func (a *archivoLocal) Tamano() int64         { return a.info.Size() }
+func (a *archivoLocal) Modificado() time.Time { return a.info.ModTime() }
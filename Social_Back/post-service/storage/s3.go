@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// ConfigS3 son los datos de conexión a un almacenamiento compatible con S3.
+type ConfigS3 struct {
+	Endpoint  string // host[:puerto], p. ej. "localhost:9000" para MinIO
+	AccessKey string
+	SecretKey string
+	Bucket    string
+	Region    string
+	SSL       bool
+}
+
+// ConfigS3DesdeEntorno lee S3_ENDPOINT, S3_ACCESS_KEY, S3_SECRET_KEY, S3_BUCKET,
+// S3_REGION y S3_SSL ("false" para MinIO local sin TLS).
+func ConfigS3DesdeEntorno() ConfigS3 {
+	return ConfigS3{
+		Endpoint:  os.Getenv("S3_ENDPOINT"),
+		AccessKey: os.Getenv("S3_ACCESS_KEY"),
+		SecretKey: os.Getenv("S3_SECRET_KEY"),
+		Bucket:    os.Getenv("S3_BUCKET"),
+		Region:    os.Getenv("S3_REGION"),
+		SSL:       os.Getenv("S3_SSL") != "false",
+	}
+}
+
+// S3 guarda los objetos en un bucket S3 o MinIO.
+type S3 struct {
+	cliente *minio.Client
+	bucket  string
+}
+
+// NuevoS3 conecta con el almacenamiento y crea el bucket si no existe.
+func NuevoS3(cfg ConfigS3) (*S3, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("faltan S3_ENDPOINT o S3_BUCKET")
+	}
+	cliente, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.SSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	existe, err := cliente.BucketExists(ctx, cfg.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		if err := cliente.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{Region: cfg.Region}); err != nil {
+			return nil, err
+		}
+	}
+	return &S3{cliente: cliente, bucket: cfg.Bucket}, nil
+}
+
+// Put sube el objeto; con tamano -1 se sube en partes.
+func (s *S3) Put(ctx context.Context, clave string, r io.Reader, tamano int64, mime string) error {
+	_, err := s.cliente.PutObject(ctx, s.bucket, clave, r, tamano, minio.PutObjectOptions{ContentType: mime})
+	return err
+}
+
+// Get abre el objeto. Las lecturas tras un Seek piden solo el rango necesario.
+func (s *S3) Get(ctx context.Context, clave string) (Objeto, error) {
+	obj, err := s.cliente.GetObject(ctx, s.bucket, clave, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	info, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		if esNoExiste(err) {
+			return nil, ErrNoExiste
+		}
+		return nil, err
+	}
+	return &objetoS3{Object: obj, info: info}, nil
+}
+
+// Existe consulta los metadatos del objeto.
+func (s *S3) Existe(ctx context.Context, clave string) (bool, error) {
+	_, err := s.cliente.StatObject(ctx, s.bucket, clave, minio.StatObjectOptions{})
+	if esNoExiste(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Copiar copia el objeto dentro del bucket sin descargarlo.
+func (s *S3) Copiar(ctx context.Context, origen, destino string) error {
+	_, err := s.cliente.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: s.bucket, Object: destino},
+		minio.CopySrcOptions{Bucket: s.bucket, Object: origen},
+	)
+	if esNoExiste(err) {
+		return ErrNoExiste
+	}
+	return err
+}
+
+// Delete borra el objeto; S3 no falla si no existía.
+func (s *S3) Delete(ctx context.Context, clave string) error {
+	return s.cliente.RemoveObject(ctx, s.bucket, clave, minio.RemoveObjectOptions{})
+}
+
+// PresignPost genera un formulario POST firmado para subir directamente al bucket.
+// La política fija la clave, el tipo y el tamaño exacto, y exige el sha256
+// declarado: S3 rechaza el contenido que no coincida antes de guardarlo.
+func (s *S3) PresignPost(ctx context.Context, clave string, subida SubidaDirecta, expira time.Duration) (string, map[string]string, error) {
+	politica := minio.NewPostPolicy()
+	if err := errors.Join(
+		politica.SetBucket(s.bucket),
+		politica.SetKey(clave),
+		politica.SetExpires(time.Now().UTC().Add(expira)),
+		politica.SetContentType(subida.MIME),
+		politica.SetContentLengthRange(subida.Tamano, subida.Tamano),
+		politica.SetChecksum(minio.NewChecksum(minio.ChecksumSHA256, subida.Sha256)),
+	); err != nil {
+		return "", nil, err
+	}
+	u, campos, err := s.cliente.PresignedPostPolicy(ctx, politica)
+	if err != nil {
+		return "", nil, err
+	}
+	return u.String(), campos, nil
+}
+
+// Sha256 lee el checksum que S3 guardó al recibir el objeto (solo existe si la
+// subida lo envió, como exige PresignPost). Los checksums compuestos de una
+// subida en partes no son el sha256 del contenido y no se usan.
+func (s *S3) Sha256(ctx context.Context, clave string) ([]byte, error) {
+	info, err := s.cliente.StatObject(ctx, s.bucket, clave, minio.StatObjectOptions{Checksum: true})
+	if esNoExiste(err) {
+		return nil, ErrNoExiste
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.ChecksumSHA256 == "" || strings.Contains(info.ChecksumSHA256, "-") {
+		return nil, ErrNoSoportado
+	}
+	return base64.StdEncoding.DecodeString(info.ChecksumSHA256)
+}
+
+// PresignGet genera una URL GET firmada. Las cabeceras Content-Type y
+// Content-Disposition se piden a S3 con los parámetros response-*.
+func (s *S3) PresignGet(ctx context.Context, clave string, expira time.Duration, cabeceras map[string]string) (string, error) {
+	params := url.Values{}
+	for k, v := range cabeceras {
+		params.Set("response-"+strings.ToLower(k), v)
+	}
+	u, err := s.cliente.PresignedGetObject(ctx, s.bucket, clave, expira, params)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// esNoExiste reconoce el error de objeto inexistente de S3.
+func esNoExiste(err error) bool {
+	if err == nil {
+		return false
+	}
+	codigo := minio.ToErrorResponse(err).Code
+	return codigo == "NoSuchKey" || codigo == "NotFound"
+}
+
+// objetoS3 adapta *minio.Object a Objeto.
+type objetoS3 struct {
+	*minio.Object
+	info minio.ObjectInfo
+}
+
+func (o *objetoS3) Tamano() int64         { return o.info.Size }
+func (o *objetoS3) Modificado() time.Time { return o.info.LastModified }
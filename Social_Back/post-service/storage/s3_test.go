@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+// s3DePrueba conecta con el MinIO local de MINIO_TEST_ENDPOINT (por ejemplo
+// "localhost:9000", levantado con `docker run -p 9000:9000 minio/minio server /data`).
+// Sin esa variable las pruebas de S3 se saltan.
+func s3DePrueba(t *testing.T) *S3 {
+	t.Helper()
+	endpoint := os.Getenv("MINIO_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("MINIO_TEST_ENDPOINT no está definido")
+	}
+	cfg := ConfigS3{
+		Endpoint:  endpoint,
+		AccessKey: "minioadmin",
+		SecretKey: "minioadmin",
+		Bucket:    "post-service-pruebas",
+	}
+	if v := os.Getenv("MINIO_TEST_ACCESS_KEY"); v != "" {
+		cfg.AccessKey = v
+		cfg.SecretKey = os.Getenv("MINIO_TEST_SECRET_KEY")
+	}
+	s, err := NuevoS3(cfg)
+	if err != nil {
+		t.Fatalf("NuevoS3: %v", err)
+	}
+	return s
+}
+
+func TestS3Objetos(t *testing.T) {
+	s := s3DePrueba(t)
+	ctx := context.Background()
+	clave := "pruebas/" + t.Name() + ".txt"
+	copia := clave + ".copia"
+	t.Cleanup(func() {
+		s.Delete(ctx, clave)
+		s.Delete(ctx, copia)
+	})
+
+	contenido := []byte("hola desde post-service")
+	if err := s.Put(ctx, clave, bytes.NewReader(contenido), int64(len(contenido)), "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := s.Copiar(ctx, clave, copia); err != nil {
+		t.Fatalf("Copiar: %v", err)
+	}
+
+	obj, err := s.Get(ctx, copia)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if _, err := obj.Seek(5, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	resto, err := io.ReadAll(obj)
+	obj.Close()
+	if err != nil || !bytes.Equal(resto, contenido[5:]) {
+		t.Fatalf("Get tras Seek = %q, %v; quiere %q", resto, err, contenido[5:])
+	}
+
+	if err := s.Delete(ctx, clave); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if existe, err := s.Existe(ctx, clave); err != nil || existe {
+		t.Fatalf("Existe tras Delete = %v, %v", existe, err)
+	}
+	if _, err := s.Get(ctx, clave); err != ErrNoExiste {
+		t.Fatalf("Get de un objeto borrado = %v, quiere ErrNoExiste", err)
+	}
+}
+
+func TestS3PresignPost(t *testing.T) {
+	s := s3DePrueba(t)
+	ctx := context.Background()
+	declarado := []byte("contenido declarado")
+	suma := sha256.Sum256(declarado)
+	subida := SubidaDirecta{MIME: "text/plain", Tamano: int64(len(declarado)), Sha256: suma[:]}
+
+	casos := []struct {
+		nombre    string
+		contenido []byte
+		acepta    bool
+	}{
+		{"contenido declarado", declarado, true},
+		{"mismo tamaño y otro contenido", []byte("contenido cambiado!"), false},
+		{"más grande que lo declarado", append(declarado, " y algo más"...), false},
+		{"vacío", nil, false},
+	}
+	for i, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			clave := "pruebas/presign-" + string(rune('a'+i))
+			t.Cleanup(func() { s.Delete(ctx, clave) })
+
+			url, campos, err := s.PresignPost(ctx, clave, subida, time.Minute)
+			if err != nil {
+				t.Fatalf("PresignPost: %v", err)
+			}
+			codigo := subirFormulario(t, url, campos, c.contenido)
+			if aceptada := codigo < 300; aceptada != c.acepta {
+				t.Fatalf("la subida respondió %d, acepta = %v", codigo, c.acepta)
+			}
+			if !c.acepta {
+				if existe, _ := s.Existe(ctx, clave); existe {
+					t.Fatal("el objeto rechazado quedó guardado")
+				}
+				return
+			}
+			got, err := s.Sha256(ctx, clave)
+			if err != nil || !bytes.Equal(got, suma[:]) {
+				t.Fatalf("Sha256 = %x, %v; quiere %x", got, err, suma)
+			}
+		})
+	}
+}
+
+// subirFormulario hace la subida que haría el navegador con lo que devuelve
+// POST /media/presign: los campos de la política y el archivo al final.
+func subirFormulario(t *testing.T, url string, campos map[string]string, contenido []byte) int {
+	t.Helper()
+	var cuerpo bytes.Buffer
+	w := multipart.NewWriter(&cuerpo)
+	for k, v := range campos {
+		w.WriteField(k, v)
+	}
+	parte, err := w.CreateFormFile("file", "archivo.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parte.Write(contenido)
+	w.Close()
+
+	res, err := http.Post(url, w.FormDataContentType(), &cuerpo)
+	if err != nil {
+		t.Fatalf("POST %s: %v", url, err)
+	}
+	res.Body.Close()
+	return res.StatusCode
+}
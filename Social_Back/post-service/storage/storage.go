@@ -0,0 +1,85 @@
+// Package storage abstrae dónde se guardan los archivos subidos: en disco local
+// o en un almacenamiento compatible con S3 (AWS S3, MinIO...).
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ErrNoExiste se devuelve al pedir un objeto que no está en el almacenamiento.
+var ErrNoExiste = errors.New("el objeto no existe")
+
+// ErrNoSoportado se devuelve cuando el driver no implementa una operación (por
+// ejemplo URLs prefirmadas en disco local).
+var ErrNoSoportado = errors.New("operación no soportada por el almacenamiento")
+
+// Objeto es un archivo leído del almacenamiento. Admite Seek para poder servir
+// rangos (Range) sin leerlo entero.
+type Objeto interface {
+	io.ReadSeekCloser
+	Tamano() int64
+	Modificado() time.Time
+}
+
+// SubidaDirecta es lo que el cliente declaró antes de subir un archivo directamente
+// al almacenamiento; la URL prefirmada solo acepta un contenido que lo cumpla.
+type SubidaDirecta struct {
+	MIME   string
+	Tamano int64  // tamaño exacto en bytes
+	Sha256 []byte // sha256 del contenido
+}
+
+// Storage es la interfaz común de los drivers de almacenamiento. Las claves son
+// rutas relativas separadas por "/" (p. ej. "ab/cd/<hash>.png").
+type Storage interface {
+	// Put guarda el contenido de r bajo clave.
+	Put(ctx context.Context, clave string, r io.Reader, tamano int64, mime string) error
+	// Get abre el objeto; devuelve ErrNoExiste si no está.
+	Get(ctx context.Context, clave string) (Objeto, error)
+	// Existe indica si hay un objeto bajo clave.
+	Existe(ctx context.Context, clave string) (bool, error)
+	// Copiar duplica un objeto dentro del mismo almacenamiento.
+	Copiar(ctx context.Context, origen, destino string) error
+	// Delete borra el objeto; no falla si no existía.
+	Delete(ctx context.Context, clave string) error
+	// PresignPost devuelve la URL y los campos del formulario POST con el que el
+	// cliente puede subir el objeto directamente al almacenamiento durante expira.
+	// El almacenamiento rechaza la subida si no cumple lo declarado en subida.
+	PresignPost(ctx context.Context, clave string, subida SubidaDirecta, expira time.Duration) (string, map[string]string, error)
+	// Sha256 devuelve el sha256 del objeto calculado por el propio almacenamiento,
+	// sin descargarlo, o ErrNoSoportado si no lo tiene.
+	Sha256(ctx context.Context, clave string) ([]byte, error)
+	// PresignGet devuelve una URL temporal de descarga directa; cabeceras permite
+	// fijar Content-Type y Content-Disposition de la respuesta.
+	PresignGet(ctx context.Context, clave string, expira time.Duration, cabeceras map[string]string) (string, error)
+}
+
+// Actual es el almacenamiento configurado por Conectar.
+var Actual Storage
+
+// Conectar elige el driver según STORAGE_DRIVER ("local" por defecto o "s3").
+func Conectar() {
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "", "local":
+		dir := os.Getenv("MEDIA_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		Actual = NuevoLocal(dir)
+		fmt.Println(" Almacenamiento local en", dir)
+	case "s3":
+		s3, err := NuevoS3(ConfigS3DesdeEntorno())
+		if err != nil {
+			panic(fmt.Sprintf("Error configurando el almacenamiento S3: %v", err))
+		}
+		Actual = s3
+		fmt.Println(" Almacenamiento S3 en el bucket", s3.bucket)
+	default:
+		panic(fmt.Sprintf("STORAGE_DRIVER desconocido: %q", driver))
+	}
+}
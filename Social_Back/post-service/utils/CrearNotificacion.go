@@ -12,7 +12,7 @@ import (
 )
 
 // CrearNotificacion guarda una notificación y la envía por WebSocket si el receptor está conectado.
-// Solo se envía si el emisor y receptor son distintos.
+// Solo se envía si el emisor y receptor son distintos y no existe un bloqueo entre ellos.
 func CrearNotificacion(
 	tipo string, // Tipo de notificación: "comentario", "like", "follow", etc.
 	de primitive.ObjectID, // Usuario que genera la notificación
@@ -23,6 +23,14 @@ func CrearNotificacion(
 	log.Println("Intentando crear notificación")
 	log.Println("Tipo:", tipo, "| De:", de.Hex(), "| Para:", para.Hex())
 
+	// No notificar entre usuarios con un bloqueo en cualquier sentido
+	if bloqueado, err := HayBloqueo(de, para); err != nil {
+		log.Println("Error verificando bloqueos:", err)
+	} else if bloqueado {
+		log.Println("No se envía notificación: existe un bloqueo entre los usuarios")
+		return
+	}
+
 	// Crear la notificación
 	noti := models.Notificacion{
 		ID:      primitive.NewObjectID(),
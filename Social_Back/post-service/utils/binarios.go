@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+var (
+	rutasBinarios   = map[string]string{}
+	rutasBinariosMu sync.Mutex
+)
+
+// binario devuelve la ruta de un programa externo usado para procesar adjuntos
+// (cwebp, ffmpeg, pdftoppm...), configurable con la variable de entorno indicada.
+// Devuelve "" si no está instalado. La búsqueda se hace una sola vez.
+func binario(variable, nombre string) string {
+	rutasBinariosMu.Lock()
+	defer rutasBinariosMu.Unlock()
+	if ruta, ok := rutasBinarios[nombre]; ok {
+		return ruta
+	}
+	buscado := os.Getenv(variable)
+	if buscado == "" {
+		buscado = nombre
+	}
+	ruta, err := exec.LookPath(buscado)
+	if err != nil {
+		log.Println(nombre, "no disponible:", err)
+		ruta = ""
+	}
+	rutasBinarios[nombre] = ruta
+	return ruta
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"context"
+	"post-service/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HayBloqueo indica si alguno de los dos usuarios bloqueó al otro.
+func HayBloqueo(a, b primitive.ObjectID) (bool, error) {
+	if a.IsZero() || b.IsZero() || a == b {
+		return false, nil
+	}
+	n, err := config.GetCollection("bloqueos").CountDocuments(context.TODO(), bson.M{"$or": []bson.M{
+		{"bloqueadorId": a, "bloqueadoId": b},
+		{"bloqueadorId": b, "bloqueadoId": a},
+	}})
+	return n > 0, err
+}
+
+// UsuariosBloqueados devuelve los usuarios con los que viewer tiene un bloqueo,
+// sin importar quién bloqueó a quién.
+func UsuariosBloqueados(viewer primitive.ObjectID) ([]primitive.ObjectID, error) {
+	if viewer.IsZero() {
+		return nil, nil
+	}
+	ctx := context.TODO()
+	coleccion := config.GetCollection("bloqueos")
+
+	bloqueados, err := coleccion.Distinct(ctx, "bloqueadoId", bson.M{"bloqueadorId": viewer})
+	if err != nil {
+		return nil, err
+	}
+	bloqueadores, err := coleccion.Distinct(ctx, "bloqueadorId", bson.M{"bloqueadoId": viewer})
+	if err != nil {
+		return nil, err
+	}
+	return IDsDeDistinct(append(bloqueados, bloqueadores...)), nil
+}
+
+// UsuariosSilenciados devuelve los usuarios que viewer silenció.
+func UsuariosSilenciados(viewer primitive.ObjectID) ([]primitive.ObjectID, error) {
+	if viewer.IsZero() {
+		return nil, nil
+	}
+	silenciados, err := config.GetCollection("silencios").Distinct(context.TODO(), "silenciadoId", bson.M{"usuarioId": viewer})
+	if err != nil {
+		return nil, err
+	}
+	return IDsDeDistinct(silenciados), nil
+}
+
+// IDsDeDistinct convierte el resultado de un Distinct en una lista de ObjectID.
+func IDsDeDistinct(valores []interface{}) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(valores))
+	for _, v := range valores {
+		if id, ok := v.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
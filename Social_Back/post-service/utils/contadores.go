@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"context"
+	"log"
+	"post-service/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// IncrementarContador suma delta al contador desnormalizado campo del post
+// (totalLikes, totalComentarios...). Los errores solo se registran: el contador
+// se puede reconstruir con InicializarContadores.
+func IncrementarContador(postID primitive.ObjectID, campo string, delta int) {
+	_, err := config.GetCollection("posts").UpdateOne(context.TODO(),
+		bson.M{"_id": postID},
+		bson.M{"$inc": bson.M{campo: delta}},
+	)
+	if err != nil {
+		log.Println("Error actualizando", campo, "del post", postID.Hex(), ":", err)
+	}
+}
+
+// InicializarContadores calcula totalLikes y totalComentarios de los posts creados
+// antes de que existieran esos campos, para que puedan ordenarse por ellos.
+func InicializarContadores() {
+	ctx := context.TODO()
+	posts := config.GetCollection("posts")
+
+	cur, err := posts.Find(ctx, bson.M{"$or": []bson.M{
+		{"totalLikes": bson.M{"$exists": false}},
+		{"totalComentarios": bson.M{"$exists": false}},
+	}})
+	if err != nil {
+		log.Println("Error buscando posts sin contadores:", err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	actualizados := 0
+	for cur.Next(ctx) {
+		var post struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cur.Decode(&post); err != nil {
+			continue
+		}
+		reacciones, likes, err := ContarReacciones(post.ID, nil)
+		if err != nil {
+			log.Println("Error contando reacciones del post", post.ID.Hex(), ":", err)
+			continue
+		}
+		comentarios, err := config.GetCollection("comentarios").CountDocuments(ctx, bson.M{"postId": post.ID})
+		if err != nil {
+			log.Println("Error contando comentarios del post", post.ID.Hex(), ":", err)
+			continue
+		}
+		_, err = posts.UpdateOne(ctx, bson.M{"_id": post.ID}, bson.M{"$set": bson.M{
+			"totalLikes":       likes,
+			"reacciones":       reacciones,
+			"totalComentarios": comentarios,
+		}})
+		if err == nil {
+			actualizados++
+		}
+	}
+	if actualizados > 0 {
+		log.Println("Contadores inicializados en", actualizados, "posts")
+	}
+}
@@ -0,0 +1,262 @@
+package utils
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"post-service/models"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	maxTextoDocumento  = 50000    // runas de texto extraído que se guardan para la búsqueda
+	maxEntradaOffice   = 20 << 20 // bytes descomprimidos que se leen de cada XML de un documento
+	anchoPortadaDoc    = 640
+	limiteHerramientas = 5 * time.Minute
+)
+
+// SinTextoAdjuntos es la proyección de los listados de posts: el texto extraído de
+// los documentos ocupa hasta maxTextoDocumento runas por adjunto y solo lo usa la
+// búsqueda.
+var SinTextoAdjuntos = bson.M{"adjuntos.texto": 0}
+
+// ErrPopplerNoDisponible indica que no están instaladas las herramientas de poppler
+// (pdfinfo, pdftoppm, pdftotext).
+var ErrPopplerNoDisponible = errors.New("poppler-utils no disponible")
+
+// patronDiapositiva reconoce las diapositivas de un .pptx y su número.
+var patronDiapositiva = regexp.MustCompile(`^ppt/slides/slide([0-9]+)\.xml$`)
+
+// ProcesarDocumento obtiene la cantidad de páginas, una portada con la primera
+// página y el texto de un PDF o documento de Office. El texto se copia a los posts
+// que lo adjuntan y entra en el índice de búsqueda.
+func ProcesarDocumento(adjunto *models.Adjunto) error {
+	dir, err := os.MkdirTemp("", "documento-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	original := filepath.Join(dir, "original"+filepath.Ext(adjunto.Archivo))
+	if err := descargarArchivo(ClaveArchivo(adjunto.Archivo), original); err != nil {
+		return err
+	}
+
+	var texto string
+	var paginas int
+	pdf := original
+	if adjunto.MIME != "application/pdf" {
+		texto, paginas, err = leerOffice(original)
+		if err != nil {
+			return err
+		}
+		// La portada requiere convertirlo a PDF con LibreOffice; si no está instalado
+		// el documento queda sin portada pero con su texto
+		pdf, err = convertirAPDF(original, dir)
+		if err != nil {
+			log.Println("No se generó la portada de", adjunto.ID.Hex(), ":", err)
+		}
+	}
+
+	resultado := bson.M{}
+	if pdf != "" {
+		pdfinfo, pdftoppm, pdftotext := binario("PDFINFO_PATH", "pdfinfo"), binario("PDFTOPPM_PATH", "pdftoppm"), binario("PDFTOTEXT_PATH", "pdftotext")
+		if pdfinfo == "" || pdftoppm == "" || pdftotext == "" {
+			return ErrPopplerNoDisponible
+		}
+		if paginas, err = paginasPDF(pdfinfo, pdf); err != nil {
+			return err
+		}
+		if adjunto.MIME == "application/pdf" {
+			out, err := ejecutarHerramienta(pdftotext, "-q", "-enc", "UTF-8", pdf, "-")
+			if err != nil {
+				return fmt.Errorf("extrayendo el texto: %w", err)
+			}
+			texto = string(out)
+		}
+
+		portada := filepath.Join(dir, "portada")
+		_, err = ejecutarHerramienta(pdftoppm, "-q", "-f", "1", "-l", "1", "-singlefile",
+			"-jpeg", "-jpegopt", "quality=80", "-scale-to", strconv.Itoa(anchoPortadaDoc), pdf, portada)
+		if err != nil {
+			return fmt.Errorf("generando la portada: %w", err)
+		}
+		archivo := adjunto.Hash + "_poster.jpg"
+		if err := subirArchivoLocal(portada+".jpg", archivo); err != nil {
+			return err
+		}
+		resultado["poster"] = "/media/" + archivo
+		resultado["derivados"] = []string{archivo}
+	}
+
+	resultado["paginas"] = paginas
+	resultado["texto"] = limpiarTexto(texto)
+	resultado["procesamiento"] = models.ProcesamientoListo
+	return ActualizarAdjunto(adjunto.ID, resultado)
+}
+
+// paginasPDF lee la cantidad de páginas con pdfinfo.
+func paginasPDF(pdfinfo, ruta string) (int, error) {
+	out, err := ejecutarHerramienta(pdfinfo, ruta)
+	if err != nil {
+		return 0, fmt.Errorf("pdfinfo: %w", err)
+	}
+	for _, linea := range strings.Split(string(out), "\n") {
+		if valor, ok := strings.CutPrefix(linea, "Pages:"); ok {
+			return strconv.Atoi(strings.TrimSpace(valor))
+		}
+	}
+	return 0, errors.New("pdfinfo no informó la cantidad de páginas")
+}
+
+// convertirAPDF convierte un documento de Office con LibreOffice en modo headless.
+// Devuelve "" si LibreOffice no está instalado.
+func convertirAPDF(ruta, dir string) (string, error) {
+	soffice := binario("SOFFICE_PATH", "soffice")
+	if soffice == "" {
+		return "", nil
+	}
+	// Un perfil propio evita chocar con otra conversión en curso
+	perfil := "-env:UserInstallation=file://" + filepath.ToSlash(filepath.Join(dir, "perfil"))
+	if _, err := ejecutarHerramienta(soffice, perfil, "--headless", "--convert-to", "pdf", "--outdir", dir, ruta); err != nil {
+		return "", err
+	}
+	pdf := strings.TrimSuffix(ruta, filepath.Ext(ruta)) + ".pdf"
+	if _, err := os.Stat(pdf); err != nil {
+		return "", err
+	}
+	return pdf, nil
+}
+
+// leerOffice extrae el texto de un .docx, .pptx o .xlsx y, si el documento la
+// declara, su cantidad de páginas o diapositivas.
+func leerOffice(ruta string) (string, int, error) {
+	z, err := zip.OpenReader(ruta)
+	if err != nil {
+		return "", 0, err
+	}
+	defer z.Close()
+
+	entradas := map[string]*zip.File{}
+	var diapositivas []string
+	for _, f := range z.File {
+		entradas[f.Name] = f
+		if patronDiapositiva.MatchString(f.Name) {
+			diapositivas = append(diapositivas, f.Name)
+		}
+	}
+	sort.Slice(diapositivas, func(i, j int) bool {
+		ni, _ := strconv.Atoi(patronDiapositiva.FindStringSubmatch(diapositivas[i])[1])
+		nj, _ := strconv.Atoi(patronDiapositiva.FindStringSubmatch(diapositivas[j])[1])
+		return ni < nj
+	})
+
+	var partes []string
+	switch {
+	case entradas["word/document.xml"] != nil:
+		partes = []string{"word/document.xml"}
+	case len(diapositivas) > 0:
+		partes = diapositivas
+	case entradas["xl/sharedStrings.xml"] != nil:
+		partes = []string{"xl/sharedStrings.xml"}
+	}
+
+	var texto strings.Builder
+	for _, nombre := range partes {
+		if err := textoXML(entradas[nombre], &texto); err != nil {
+			return "", 0, err
+		}
+		if texto.Len() > maxTextoDocumento*utf8.UTFMax {
+			break
+		}
+	}
+
+	paginas := len(diapositivas)
+	if app := entradas["docProps/app.xml"]; app != nil {
+		var props struct {
+			Paginas      int `xml:"Pages"`
+			Diapositivas int `xml:"Slides"`
+		}
+		if r, err := app.Open(); err == nil {
+			if xml.NewDecoder(io.LimitReader(r, maxEntradaOffice)).Decode(&props) == nil {
+				paginas = max(paginas, props.Paginas, props.Diapositivas)
+			}
+			r.Close()
+		}
+	}
+	return texto.String(), paginas, nil
+}
+
+// textoXML agrega a texto el contenido de los elementos <t> (w:t, a:t o los de
+// sharedStrings) de una parte del documento, con un salto por párrafo o celda.
+func textoXML(f *zip.File, texto *strings.Builder) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	dec := xml.NewDecoder(io.LimitReader(r, maxEntradaOffice))
+	enTexto := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("leyendo %s: %w", f.Name, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			enTexto = t.Name.Local == "t"
+		case xml.EndElement:
+			enTexto = false
+			if t.Name.Local == "p" || t.Name.Local == "si" {
+				texto.WriteByte('\n')
+			}
+		case xml.CharData:
+			if enTexto {
+				texto.Write(t)
+			}
+		}
+	}
+}
+
+// limpiarTexto compacta los espacios del texto extraído y lo recorta a
+// maxTextoDocumento runas.
+func limpiarTexto(texto string) string {
+	texto = strings.Join(strings.Fields(strings.ToValidUTF8(texto, " ")), " ")
+	if utf8.RuneCountInString(texto) > maxTextoDocumento {
+		texto = string([]rune(texto)[:maxTextoDocumento])
+	}
+	return texto
+}
+
+// ejecutarHerramienta corre un programa externo con tiempo límite y devuelve su
+// salida estándar; si falla incluye la salida de error.
+func ejecutarHerramienta(ruta string, args ...string) ([]byte, error) {
+	ctx, cancelar := context.WithTimeout(context.Background(), limiteHerramientas)
+	defer cancelar()
+	cmd := exec.CommandContext(ctx, ruta, args...)
+	var errores strings.Builder
+	cmd.Stderr = &errores
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %s", err, errores.String())
+	}
+	return out, nil
+}
@@ -0,0 +1,252 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"post-service/config"
+	"post-service/models"
+	"post-service/storage"
+	"slices"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const diasPapeleraDefecto = 30
+
+// coleccionesDelPost guardan documentos con el postId del post al que pertenecen
+// y se vacían al purgarlo.
+var coleccionesDelPost = []string{
+	"timelines", "reacciones", "comentarios", "notificaciones", "post_revisiones", "tag_usos",
+	"guardados",
+}
+
+// DiasPapelera es el plazo para restaurar un post eliminado (PAPELERA_DIAS, por
+// defecto 30). Con 0 los posts se purgan en el momento.
+func DiasPapelera() int {
+	dias, err := strconv.Atoi(os.Getenv("PAPELERA_DIAS"))
+	if err != nil || dias < 0 {
+		return diasPapeleraDefecto
+	}
+	return dias
+}
+
+// PlazoRestauracion devuelve hasta cuándo se puede restaurar un post eliminado en
+// el momento indicado.
+func PlazoRestauracion(eliminadoEn time.Time) time.Time {
+	return eliminadoEn.AddDate(0, 0, DiasPapelera())
+}
+
+// MoverAPapelera saca el post de "posts" (y de los timelines) y lo guarda en
+// "posts_papelera". Sus comentarios, reacciones y archivos se conservan hasta la
+// purga para poder restaurarlo tal cual.
+func MoverAPapelera(post models.Post) error {
+	ctx := context.TODO()
+	eliminado := models.PostEliminado{Post: post, EliminadoEn: time.Now()}
+	_, err := config.GetCollection("posts_papelera").ReplaceOne(ctx, bson.M{"_id": post.ID}, eliminado, options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	if _, err := config.GetCollection("posts").DeleteOne(ctx, bson.M{"_id": post.ID}); err != nil {
+		return err
+	}
+	QuitarPostDeTimelines(post.ID)
+	if original := IDOriginal(post); original != nil {
+		RecalcularReposts(*original)
+	}
+	return nil
+}
+
+// RestaurarDePapelera devuelve el post a "posts" y a los timelines de sus seguidores.
+func RestaurarDePapelera(eliminado models.PostEliminado) error {
+	ctx := context.TODO()
+	_, err := config.GetCollection("posts").ReplaceOne(ctx, bson.M{"_id": eliminado.ID}, eliminado.Post, options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	if _, err := config.GetCollection("posts_papelera").DeleteOne(ctx, bson.M{"_id": eliminado.ID}); err != nil {
+		return err
+	}
+	if original := IDOriginal(eliminado.Post); original != nil {
+		RecalcularReposts(*original)
+	}
+	go DistribuirPost(eliminado.Post)
+	return nil
+}
+
+// purgaPost es el registro de una purga en curso (colección "purgas_posts"). Cada
+// paso es idempotente y se marca al terminar, así una purga interrumpida se
+// retoma desde donde quedó (ver IniciarLimpieza).
+type purgaPost struct {
+	ID          primitive.ObjectID   `bson:"_id"` // id del post
+	Adjuntos    []primitive.ObjectID `bson:"adjuntos"`
+	Original    *primitive.ObjectID  `bson:"original,omitempty"` // post compartido o citado
+	Completados []string             `bson:"completados"`
+	Fecha       time.Time            `bson:"fecha"`
+}
+
+// pasosPurga se ejecutan en orden: primero desaparece el post, para que no reciba
+// nuevas interacciones, y después todo lo que dependía de él.
+var pasosPurga = []struct {
+	nombre   string
+	ejecutar func(ctx context.Context, p purgaPost) error
+}{
+	{"post", func(ctx context.Context, p purgaPost) error {
+		for _, coleccion := range []string{"posts", "posts_papelera"} {
+			if _, err := config.GetCollection(coleccion).DeleteOne(ctx, bson.M{"_id": p.ID}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{"reposts", func(ctx context.Context, p purgaPost) error {
+		// Los reposts no tienen contenido propio y desaparecen con el original (las
+		// citas se conservan sin él). Como no tienen adjuntos basta con borrar lo que
+		// cuelga de ellos; si algo queda a medias lo recoge la limpieza de huérfanos
+		for _, coleccion := range []string{"posts", "posts_papelera"} {
+			valores, err := config.GetCollection(coleccion).Distinct(ctx, "_id", bson.M{"repostDe": p.ID})
+			if err != nil {
+				return err
+			}
+			ids := IDsDeDistinct(valores)
+			if len(ids) == 0 {
+				continue
+			}
+			for _, dependiente := range coleccionesDelPost {
+				if _, err := config.GetCollection(dependiente).DeleteMany(ctx, bson.M{"postId": bson.M{"$in": ids}}); err != nil {
+					return fmt.Errorf("%s: %w", dependiente, err)
+				}
+			}
+			if _, err := config.GetCollection(coleccion).DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{"dependientes", func(ctx context.Context, p purgaPost) error {
+		for _, coleccion := range coleccionesDelPost {
+			if _, err := config.GetCollection(coleccion).DeleteMany(ctx, bson.M{"postId": p.ID}); err != nil {
+				return fmt.Errorf("%s: %w", coleccion, err)
+			}
+		}
+		return nil
+	}},
+	{"adjuntos", func(ctx context.Context, p purgaPost) error {
+		for _, id := range p.Adjuntos {
+			if err := liberarAdjunto(ctx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{"original", func(ctx context.Context, p purgaPost) error {
+		if p.Original != nil {
+			RecalcularReposts(*p.Original)
+		}
+		return nil
+	}},
+}
+
+// PurgarPost borra definitivamente un post (esté publicado o en la papelera) con
+// sus comentarios, reacciones, notificaciones, revisiones y los archivos que
+// ningún otro post usa.
+func PurgarPost(post models.Post) error {
+	adjuntos := make([]primitive.ObjectID, 0, len(post.Adjuntos))
+	for _, a := range post.Adjuntos {
+		adjuntos = append(adjuntos, a.ID)
+	}
+	inicial := bson.M{"adjuntos": adjuntos, "completados": []string{}, "fecha": time.Now()}
+	if original := IDOriginal(post); original != nil {
+		inicial["original"] = *original
+	}
+	_, err := config.GetCollection("purgas_posts").UpdateOne(context.TODO(),
+		bson.M{"_id": post.ID},
+		bson.M{"$setOnInsert": inicial},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+	return continuarPurga(post.ID)
+}
+
+// continuarPurga ejecuta los pasos pendientes de la purga del post.
+func continuarPurga(postID primitive.ObjectID) error {
+	ctx := context.TODO()
+	purgas := config.GetCollection("purgas_posts")
+	var purga purgaPost
+	if err := purgas.FindOne(ctx, bson.M{"_id": postID}).Decode(&purga); err != nil {
+		return err
+	}
+	for _, paso := range pasosPurga {
+		if slices.Contains(purga.Completados, paso.nombre) {
+			continue
+		}
+		if err := paso.ejecutar(ctx, purga); err != nil {
+			return fmt.Errorf("purgando el post %s (%s): %w", postID.Hex(), paso.nombre, err)
+		}
+		if _, err := purgas.UpdateOne(ctx, bson.M{"_id": postID}, bson.M{"$addToSet": bson.M{"completados": paso.nombre}}); err != nil {
+			return err
+		}
+	}
+	_, err := purgas.DeleteOne(ctx, bson.M{"_id": postID})
+	return err
+}
+
+// liberarAdjunto borra un adjunto que ya no usa ningún post, junto con sus
+// trabajos de procesamiento y sus archivos si ningún otro adjunto tiene el mismo
+// contenido.
+func liberarAdjunto(ctx context.Context, adjuntoID primitive.ObjectID) error {
+	for _, coleccion := range []string{"posts", "posts_papelera"} {
+		usos, err := config.GetCollection(coleccion).CountDocuments(ctx, bson.M{"adjuntos._id": adjuntoID}, options.Count().SetLimit(1))
+		if err != nil {
+			return err
+		}
+		if usos > 0 {
+			return nil
+		}
+	}
+
+	adjuntos := config.GetCollection("adjuntos")
+	var adjunto models.Adjunto
+	err := adjuntos.FindOne(ctx, bson.M{"_id": adjuntoID}).Decode(&adjunto)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := config.GetCollection("trabajos_media").DeleteMany(ctx, bson.M{"adjuntoId": adjuntoID}); err != nil {
+		return err
+	}
+	if _, err := adjuntos.DeleteOne(ctx, bson.M{"_id": adjuntoID}); err != nil {
+		return err
+	}
+	return borrarArchivosSinUso(ctx, adjunto)
+}
+
+// borrarArchivosSinUso borra del almacenamiento el archivo de un adjunto ya borrado
+// y sus derivados, salvo que otro adjunto comparta el contenido.
+func borrarArchivosSinUso(ctx context.Context, adjunto models.Adjunto) error {
+	if adjunto.Estado == "pendiente" {
+		return storage.Actual.Delete(ctx, ClavePendiente(adjunto.ID.Hex()))
+	}
+	if adjunto.Hash == "" {
+		return nil
+	}
+	compartido, err := config.GetCollection("adjuntos").CountDocuments(ctx, bson.M{"hash": adjunto.Hash}, options.Count().SetLimit(1))
+	if err != nil || compartido > 0 {
+		return err
+	}
+	for _, archivo := range append([]string{adjunto.Archivo}, adjunto.Derivados...) {
+		if err := storage.Actual.Delete(ctx, ClaveArchivo(archivo)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
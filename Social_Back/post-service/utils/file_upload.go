@@ -1,44 +1,418 @@
 package utils
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image"
+	_ "image/gif" // registra los decodificadores usados por image.DecodeConfig
+	_ "image/jpeg"
+	_ "image/png"
 	"io"
+	"log"
+	"net/http"
 	"os"
 	"path/filepath"
+	"post-service/models"
+	"post-service/storage"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/labstack/echo/v4"
 )
 
-// SubirArchivo guarda un archivo localmente y retorna la ruta relativa
-func SubirArchivo(c echo.Context, campo string) (string, error) {
+// Errores de validación de SubirArchivo.
+var (
+	ErrArchivoMuyGrande = errors.New("el archivo supera el tamaño permitido")
+	ErrTipoNoPermitido  = errors.New("tipo de archivo no permitido")
+)
+
+// tipoArchivo describe un tipo MIME aceptado en las subidas.
+type tipoArchivo struct {
+	tipo   string // imagen, video, documento
+	ext    string
+	maximo int64 // tamaño máximo en bytes
+}
+
+// tiposPermitidos son los tipos MIME aceptados, detectados a partir del contenido
+// (nunca a partir del nombre ni de la cabecera enviada por el cliente).
+var tiposPermitidos = map[string]tipoArchivo{
+	"image/jpeg":      {"imagen", ".jpg", 10 << 20},
+	"image/png":       {"imagen", ".png", 10 << 20},
+	"image/gif":       {"imagen", ".gif", 10 << 20},
+	"image/webp":      {"imagen", ".webp", 10 << 20},
+	"video/mp4":       {"video", ".mp4", 200 << 20},
+	"video/webm":      {"video", ".webm", 200 << 20},
+	"application/pdf": {"documento", ".pdf", 25 << 20},
+	mimeDocx:          {"documento", ".docx", 25 << 20},
+	mimePptx:          {"documento", ".pptx", 50 << 20},
+	mimeXlsx:          {"documento", ".xlsx", 25 << 20},
+}
+
+// MaxCuerpoSubida limita el cuerpo de POST /media antes de leerlo: el archivo más
+// grande permitido (un video de 200 MB) más un margen para el resto del multipart.
+const MaxCuerpoSubida = 200<<20 + 1<<20
+
+// Tipos MIME de los documentos de Office (OOXML).
+const (
+	mimeDocx = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	mimePptx = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+	mimeXlsx = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// mimeZip es lo que DetectContentType devuelve para un documento de Office: con
+// los primeros bytes solo se sabe que es un ZIP, el tipo real lo decide tipoOffice.
+const mimeZip = "application/zip"
+
+// ClaveArchivo devuelve la clave de almacenamiento de un archivo. Se reparte en
+// "carpetas" por los primeros caracteres del hash para no acumular miles de
+// archivos en una sola.
+func ClaveArchivo(archivo string) string {
+	return archivo[:2] + "/" + archivo[2:4] + "/" + archivo
+}
+
+// ClavePendiente es donde se sube un archivo con URL prefirmada hasta que se confirma.
+func ClavePendiente(adjuntoID string) string {
+	return "pendientes/" + adjuntoID
+}
+
+// detectarTipo identifica el tipo real del archivo a partir de sus primeros bytes.
+// Para un ZIP devuelve mimeZip con el tamaño máximo de los documentos de Office;
+// hay que confirmarlo con tipoOffice cuando se tiene el archivo completo.
+func detectarTipo(cabecera []byte) (string, tipoArchivo, error) {
+	mime, _, _ := strings.Cut(http.DetectContentType(cabecera), ";")
+	if mime == mimeZip {
+		return mime, tiposPermitidos[mimePptx], nil
+	}
+	permitido, ok := tiposPermitidos[mime]
+	if !ok {
+		return "", tipoArchivo{}, ErrTipoNoPermitido
+	}
+	return mime, permitido, nil
+}
+
+// ValidarSubidaDirecta comprueba el tipo y tamaño declarados antes de entregar una
+// URL prefirmada. El contenido real se vuelve a verificar en ConfirmarSubidaDirecta.
+func ValidarSubidaDirecta(mime string, tamano int64) error {
+	permitido, ok := tiposPermitidos[mime]
+	if !ok {
+		return ErrTipoNoPermitido
+	}
+	if tamano <= 0 || tamano > permitido.maximo {
+		return ErrArchivoMuyGrande
+	}
+	return nil
+}
+
+// SubirArchivo valida y guarda el archivo del campo multipart indicado y devuelve
+// sus metadatos (sin ID ni SubidoPor). El nombre en el almacenamiento es el sha256
+// del contenido, así que el nombre enviado por el cliente nunca se usa como ruta.
+// Los JPEG se guardan sin EXIF, que puede incluir la ubicación GPS.
+func SubirArchivo(c echo.Context, campo string) (*models.Adjunto, error) {
 	file, err := c.FormFile(campo)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	src, err := file.Open()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer src.Close()
 
-	// Crear carpeta si no existe
-	err = os.MkdirAll("uploads", os.ModePerm)
+	// Detectar el tipo real a partir de los primeros bytes
+	cabecera := make([]byte, 512)
+	n, err := io.ReadFull(src, cabecera)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	cabecera = cabecera[:n]
+	mime, permitido, err := detectarTipo(cabecera)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if file.Size > permitido.maximo {
+		return nil, ErrArchivoMuyGrande
 	}
 
-	dstPath := filepath.Join("uploads", file.Filename)
-	dst, err := os.Create(dstPath)
+	// Se escribe en un temporal mientras se calcula el hash, sin confiar en file.Size
+	tmp, err := os.CreateTemp("", "subida-*")
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	hash := sha256.New()
+	destino := io.MultiWriter(tmp, hash)
+	if _, err := destino.Write(cabecera); err != nil {
+		return nil, err
+	}
+	copiados, err := io.Copy(destino, io.LimitReader(src, permitido.maximo-int64(n)+1))
+	if err != nil {
+		return nil, err
+	}
+	tamano := int64(n) + copiados
+	if tamano > permitido.maximo {
+		return nil, ErrArchivoMuyGrande
+	}
+	if mime == mimeZip {
+		if mime, permitido, err = tipoOffice(tmp, tamano); err != nil {
+			return nil, err
+		}
+		if tamano > permitido.maximo {
+			return nil, ErrArchivoMuyGrande
+		}
+	}
+
+	suma := hex.EncodeToString(hash.Sum(nil))
+	if mime == "image/jpeg" {
+		if suma, tamano, err = limpiarJPEG(tmp); err != nil {
+			return nil, err
+		}
+	}
+	archivo := suma + permitido.ext
+	adjunto := nuevoAdjunto(suma, archivo, file.Filename, mime, permitido.tipo, tamano)
+	if permitido.tipo == "imagen" {
+		if _, err := tmp.Seek(0, io.SeekStart); err == nil {
+			adjunto.Ancho, adjunto.Alto = dimensionesImagen(tmp)
+		}
+		if demasiadosPixeles(adjunto.Ancho, adjunto.Alto) {
+			return nil, ErrArchivoMuyGrande
+		}
+	}
+
+	// Un archivo idéntico ya guardado se reutiliza
+	ctx := context.TODO()
+	clave := ClaveArchivo(archivo)
+	existe, err := storage.Actual.Existe(ctx, clave)
+	if err != nil {
+		return nil, err
+	}
+	if !existe {
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if err := storage.Actual.Put(ctx, clave, tmp, tamano, mime); err != nil {
+			return nil, err
+		}
+	}
+	return adjunto, nil
+}
+
+// limpiarJPEG reescribe el temporal de una subida sin los metadatos que quita
+// quitarMetadatosJPEG y devuelve el hash y el tamaño de lo que quedó.
+func limpiarJPEG(f *os.File) (string, int64, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", 0, err
+	}
+	datos, err := io.ReadAll(f)
+	if err != nil {
+		return "", 0, err
+	}
+	limpio, err := quitarMetadatosJPEG(datos)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := f.Truncate(0); err != nil {
+		return "", 0, err
 	}
-	defer dst.Close()
+	if _, err := f.WriteAt(limpio, 0); err != nil {
+		return "", 0, err
+	}
+	suma := sha256.Sum256(limpio)
+	return hex.EncodeToString(suma[:]), int64(len(limpio)), nil
+}
+
+// ConfirmarSubidaDirecta verifica un archivo subido con URL prefirmada a
+// ClavePendiente(adjuntoID): detecta su tipo real, comprueba el tamaño, calcula su
+// hash y lo mueve a su clave definitiva (un JPEG se guarda sin metadatos). Si no
+// es válido se borra.
+func ConfirmarSubidaDirecta(adjuntoID, nombre string) (*models.Adjunto, error) {
+	ctx := context.TODO()
+	pendiente := ClavePendiente(adjuntoID)
+	obj, err := storage.Actual.Get(ctx, pendiente)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	cabecera := make([]byte, 512)
+	n, err := io.ReadFull(obj, cabecera)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	cabecera = cabecera[:n]
+	mime, permitido, err := detectarTipo(cabecera)
+	if err == nil && mime == mimeZip {
+		mime, permitido, err = tipoOffice(lectorEnPosicion{obj}, obj.Tamano())
+	}
+	if err == nil && obj.Tamano() > permitido.maximo {
+		err = ErrArchivoMuyGrande
+	}
+	ancho, alto := 0, 0
+	if err == nil && permitido.tipo == "imagen" {
+		if _, err = obj.Seek(0, io.SeekStart); err == nil {
+			ancho, alto = dimensionesImagen(obj)
+			if demasiadosPixeles(ancho, alto) {
+				err = ErrArchivoMuyGrande
+			}
+		}
+	}
+	// Un JPEG se vuelve a subir sin metadatos en lugar de copiar el original
+	var limpio []byte
+	if err == nil && mime == "image/jpeg" {
+		var datos []byte
+		if _, err = obj.Seek(0, io.SeekStart); err == nil {
+			if datos, err = io.ReadAll(obj); err == nil {
+				limpio, err = quitarMetadatosJPEG(datos)
+			}
+		}
+	}
+	if err != nil {
+		if errors.Is(err, ErrTipoNoPermitido) || errors.Is(err, ErrArchivoMuyGrande) {
+			storage.Actual.Delete(ctx, pendiente)
+		}
+		return nil, err
+	}
+
+	var suma string
+	tamano := obj.Tamano()
+	if limpio != nil {
+		hash := sha256.Sum256(limpio)
+		suma, tamano = hex.EncodeToString(hash[:]), int64(len(limpio))
+	} else if suma, err = hashSubidaDirecta(ctx, pendiente, obj); err != nil {
+		return nil, err
+	}
+	archivo := suma + permitido.ext
+	adjunto := nuevoAdjunto(suma, archivo, nombre, mime, permitido.tipo, tamano)
+	adjunto.Ancho, adjunto.Alto = ancho, alto
 
-	_, err = io.Copy(dst, src)
+	clave := ClaveArchivo(archivo)
+	existe, err := storage.Actual.Existe(ctx, clave)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case existe:
+	case limpio != nil:
+		err = storage.Actual.Put(ctx, clave, bytes.NewReader(limpio), tamano, mime)
+	default:
+		err = storage.Actual.Copiar(ctx, pendiente, clave)
+	}
 	if err != nil {
+		return nil, err
+	}
+	if err := storage.Actual.Delete(ctx, pendiente); err != nil {
+		log.Println("Error borrando subida pendiente", pendiente, ":", err)
+	}
+	return adjunto, nil
+}
+
+// hashSubidaDirecta usa el sha256 que el almacenamiento verificó al recibir la
+// subida, así el archivo no vuelve a pasar por post-service. Solo si el
+// almacenamiento no lo guardó se recorre el objeto entero.
+func hashSubidaDirecta(ctx context.Context, clave string, obj storage.Objeto) (string, error) {
+	suma, err := storage.Actual.Sha256(ctx, clave)
+	if err == nil {
+		return hex.EncodeToString(suma), nil
+	}
+	if !errors.Is(err, storage.ErrNoSoportado) {
+		return "", err
+	}
+	if _, err := obj.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, obj); err != nil {
 		return "", err
 	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// tipoOffice revisa las entradas de un ZIP para saber si es un documento de Word,
+// PowerPoint o Excel. Cualquier otro ZIP se rechaza.
+func tipoOffice(r io.ReaderAt, tamano int64) (string, tipoArchivo, error) {
+	z, err := zip.NewReader(r, tamano)
+	if err != nil {
+		return "", tipoArchivo{}, ErrTipoNoPermitido
+	}
+	mime, tipos := "", false
+	for _, f := range z.File {
+		switch {
+		case f.Name == "[Content_Types].xml":
+			tipos = true
+		case strings.HasPrefix(f.Name, "word/"):
+			mime = mimeDocx
+		case strings.HasPrefix(f.Name, "ppt/"):
+			mime = mimePptx
+		case strings.HasPrefix(f.Name, "xl/"):
+			mime = mimeXlsx
+		}
+	}
+	if !tipos || mime == "" {
+		return "", tipoArchivo{}, ErrTipoNoPermitido
+	}
+	return mime, tiposPermitidos[mime], nil
+}
+
+// lectorEnPosicion adapta un io.ReadSeeker a io.ReaderAt para leer un ZIP guardado
+// en el almacenamiento. No admite lecturas concurrentes.
+type lectorEnPosicion struct{ io.ReadSeeker }
+
+func (l lectorEnPosicion) ReadAt(p []byte, pos int64) (int, error) {
+	if _, err := l.Seek(pos, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(l, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF // lo que espera io.ReaderAt al llegar al final
+	}
+	return n, err
+}
+
+// nuevoAdjunto arma los metadatos comunes de un archivo ya validado.
+func nuevoAdjunto(hash, archivo, nombre, mime, tipo string, tamano int64) *models.Adjunto {
+	return &models.Adjunto{
+		Hash:    hash,
+		Archivo: archivo,
+		Nombre:  nombreSeguro(nombre),
+		MIME:    mime,
+		Tipo:    tipo,
+		Tamano:  tamano,
+		URL:     "/media/" + archivo,
+		Fecha:   time.Now(),
+	}
+}
+
+// dimensionesImagen lee el ancho y alto de una imagen; devuelve 0, 0 si el formato
+// no se puede decodificar.
+func dimensionesImagen(r io.Reader) (int, int) {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return 0, 0
+	}
+	return cfg.Width, cfg.Height
+}
 
-	// Retornar ruta (puedes adaptarlo para S3 en el futuro)
-	return "/" + dstPath, nil
+// nombreSeguro limpia el nombre original para mostrarlo: sin rutas ni caracteres
+// de control y con un largo razonable.
+func nombreSeguro(nombre string) string {
+	nombre = filepath.Base(strings.ReplaceAll(nombre, "\\", "/"))
+	nombre = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, nombre)
+	if r := []rune(nombre); len(r) > 100 {
+		nombre = string(r[:100])
+	}
+	if nombre == "." || nombre == "/" {
+		return ""
+	}
+	return nombre
 }
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+// zipCon arma en memoria un ZIP con las entradas indicadas (vacías).
+func zipCon(t *testing.T, nombres ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, n := range nombres {
+		if _, err := w.Create(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestTipoOffice(t *testing.T) {
+	casos := []struct {
+		nombre string
+		datos  []byte
+		quiere string // "" si se rechaza
+	}{
+		{"docx", zipCon(t, "[Content_Types].xml", "_rels/.rels", "word/document.xml"), mimeDocx},
+		{"pptx", zipCon(t, "[Content_Types].xml", "ppt/presentation.xml", "ppt/slides/slide1.xml"), mimePptx},
+		{"xlsx", zipCon(t, "[Content_Types].xml", "xl/workbook.xml"), mimeXlsx},
+		{"ZIP cualquiera", zipCon(t, "foto.jpg", "notas.txt"), ""},
+		{"ZIP vacío", zipCon(t), ""},
+		{"sin [Content_Types].xml", zipCon(t, "word/document.xml"), ""},
+		{"solo [Content_Types].xml", zipCon(t, "[Content_Types].xml"), ""},
+		{"EPUB", zipCon(t, "mimetype", "META-INF/container.xml", "OEBPS/content.opf"), ""},
+		{"JAR", zipCon(t, "META-INF/MANIFEST.MF", "com/ejemplo/Main.class"), ""},
+		{"ODT", zipCon(t, "mimetype", "[Content_Types].xml", "content.xml"), ""},
+		{"nombre parecido sin carpeta", zipCon(t, "[Content_Types].xml", "word.xml", "xlsx", "ppt"), ""},
+		{"no es ZIP", []byte("PK\x03\x04 esto no es un zip"), ""},
+		{"ZIP recortado", zipCon(t, "[Content_Types].xml", "word/document.xml")[:40], ""},
+		{"vacío", nil, ""},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			mime, permitido, err := tipoOffice(bytes.NewReader(c.datos), int64(len(c.datos)))
+			if c.quiere == "" {
+				if err != ErrTipoNoPermitido {
+					t.Errorf("tipoOffice = %q, %v; quiere ErrTipoNoPermitido", mime, err)
+				}
+				return
+			}
+			if err != nil || mime != c.quiere || permitido != tiposPermitidos[c.quiere] {
+				t.Errorf("tipoOffice = %q, %+v, %v; quiere %q", mime, permitido, err, c.quiere)
+			}
+		})
+	}
+}
+
+func TestDetectarTipo(t *testing.T) {
+	casos := []struct {
+		nombre   string
+		cabecera []byte
+		quiere   string // "" si se rechaza
+	}{
+		{"PNG", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "image/png"},
+		{"JPEG", []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"), "image/jpeg"},
+		{"PDF", []byte("%PDF-1.7\n"), "application/pdf"},
+		{"ZIP (a confirmar con tipoOffice)", zipCon(t, "a"), mimeZip},
+		{"HTML", []byte("<!DOCTYPE html><script>alert(1)</script>"), ""},
+		{"SVG", []byte(`<svg xmlns="http://www.w3.org/2000/svg" onload="alert(1)"/>`), ""},
+		{"ejecutable", []byte("MZ\x90\x00\x03\x00\x00\x00"), ""},
+		{"texto", []byte("hola"), ""},
+		{"vacío", nil, ""},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			mime, _, err := detectarTipo(c.cabecera)
+			if c.quiere == "" {
+				if err != ErrTipoNoPermitido {
+					t.Errorf("detectarTipo = %q, %v; quiere ErrTipoNoPermitido", mime, err)
+				}
+				return
+			}
+			if err != nil || mime != c.quiere {
+				t.Errorf("detectarTipo = %q, %v; quiere %q", mime, err, c.quiere)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"context"
+	"post-service/config"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GuardarPost guarda el post para el usuario si aún no lo tenía y lo agrega a las
+// colecciones indicadas. Es idempotente.
+func GuardarPost(usuarioID, postID primitive.ObjectID, colecciones []primitive.ObjectID) error {
+	update := bson.M{"$setOnInsert": bson.M{"fecha": time.Now()}}
+	if len(colecciones) > 0 {
+		update["$addToSet"] = bson.M{"colecciones": bson.M{"$each": colecciones}}
+	} else {
+		update["$setOnInsert"].(bson.M)["colecciones"] = []primitive.ObjectID{}
+	}
+	filtro := bson.M{"usuarioId": usuarioID, "postId": postID}
+	opts := options.Update().SetUpsert(true)
+	_, err := config.GetCollection("guardados").UpdateOne(context.TODO(), filtro, update, opts)
+	if mongo.IsDuplicateKeyError(err) {
+		// Otra petición lo guardó a la vez: ahora ya existe y se actualiza
+		_, err = config.GetCollection("guardados").UpdateOne(context.TODO(), filtro, update, opts)
+	}
+	return err
+}
+
+// PostsGuardados indica cuáles de los posts guardó el usuario. Un usuario cero
+// (visitante anónimo) no tiene guardados.
+func PostsGuardados(usuarioID primitive.ObjectID, postIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	guardados := map[primitive.ObjectID]bool{}
+	if usuarioID.IsZero() || len(postIDs) == 0 {
+		return guardados, nil
+	}
+	cur, err := config.GetCollection("guardados").Find(context.TODO(),
+		bson.M{"usuarioId": usuarioID, "postId": bson.M{"$in": postIDs}},
+		options.Find().SetProjection(bson.M{"postId": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var filas []struct {
+		PostID primitive.ObjectID `bson:"postId"`
+	}
+	if err := cur.All(context.TODO(), &filas); err != nil {
+		return nil, err
+	}
+	for _, f := range filas {
+		guardados[f.PostID] = true
+	}
+	return guardados, nil
+}
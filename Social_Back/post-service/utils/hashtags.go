@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"context"
+	"log"
+	"post-service/config"
+	"post-service/models"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// largoMaximoTag evita guardar "hashtags" absurdamente largos.
+const largoMaximoTag = 50
+
+// patronHashtag encuentra #palabra cuando el # no va pegado a otra palabra (así
+// "C#" o "a#b" no cuentan como hashtags).
+var patronHashtag = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#])#([\p{L}\p{N}_]+)`)
+
+// NormalizarTag deja un tag en su forma canónica: sin #, en minúsculas, sin tildes
+// y solo con letras, números y guión bajo. Devuelve "" si no queda nada válido.
+func NormalizarTag(tag string) string {
+	tag = Normalizar(strings.TrimSpace(strings.TrimLeft(tag, "#")))
+	tag = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) || r == '_' {
+			return r
+		}
+		return -1
+	}, tag)
+	if len([]rune(tag)) > largoMaximoTag {
+		tag = string([]rune(tag)[:largoMaximoTag])
+	}
+	return tag
+}
+
+// ExtraerHashtags devuelve, normalizados y sin repetir, los hashtags de un texto.
+// Se ignoran los puramente numéricos (#1, #2024).
+func ExtraerHashtags(texto string) []string {
+	var tags []string
+	for _, m := range patronHashtag.FindAllStringSubmatch(texto, -1) {
+		tag := NormalizarTag(m[1])
+		if tag == "" || strings.IndexFunc(tag, unicode.IsLetter) == -1 {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	return UnirTags(tags)
+}
+
+// UnirTags normaliza y combina varias listas de tags conservando el primer orden de aparición.
+func UnirTags(listas ...[]string) []string {
+	vistos := map[string]bool{}
+	tags := []string{}
+	for _, lista := range listas {
+		for _, t := range lista {
+			t = NormalizarTag(t)
+			if t != "" && !vistos[t] {
+				vistos[t] = true
+				tags = append(tags, t)
+			}
+		}
+	}
+	return tags
+}
+
+// RegistrarUsoTags guarda un uso de cada tag para el cálculo de tendencias.
+func RegistrarUsoTags(tags []string, postID, usuarioID primitive.ObjectID, origen string) {
+	if len(tags) == 0 {
+		return
+	}
+	ahora := time.Now()
+	usos := make([]interface{}, 0, len(tags))
+	for _, t := range tags {
+		usos = append(usos, models.UsoTag{
+			ID:        primitive.NewObjectID(),
+			Tag:       t,
+			PostID:    postID,
+			UsuarioID: usuarioID,
+			Origen:    origen,
+			Fecha:     ahora,
+		})
+	}
+	if _, err := config.GetCollection("tag_usos").InsertMany(context.TODO(), usos); err != nil {
+		log.Println("Error registrando uso de tags:", err)
+	}
+}
+
+// tagSinNormalizar coincide con tags que tienen algo más que minúsculas ASCII,
+// dígitos y "_": los guardados antes de NormalizarTag. Los tags normalizados en
+// otros alfabetos también coinciden, pero migrarlos no los cambia.
+var tagSinNormalizar = bson.M{"$regex": "[^a-z0-9_]"}
+
+// MigrarTags normaliza con NormalizarTag los tags de los posts (también en la
+// papelera) y de los usos recientes guardados antes de normalizarlos, para que
+// sigan apareciendo en ?tag= y en las páginas de tags. Solo toca los documentos
+// con tags sin normalizar, así que volver a ejecutarla no hace nada.
+func MigrarTags() {
+	ctx := context.TODO()
+	migrados := 0
+	for _, coleccion := range []string{"posts", "posts_papelera"} {
+		col := config.GetCollection(coleccion)
+		cur, err := col.Find(ctx, bson.M{"tags": tagSinNormalizar}, options.Find().SetProjection(bson.M{"tags": 1}))
+		if err != nil {
+			log.Println("Error buscando tags para migrar en", coleccion, ":", err)
+			continue
+		}
+		for cur.Next(ctx) {
+			var doc struct {
+				ID   primitive.ObjectID `bson:"_id"`
+				Tags []string           `bson:"tags"`
+			}
+			if err := cur.Decode(&doc); err != nil {
+				continue
+			}
+			tags := UnirTags(doc.Tags)
+			if slices.Equal(tags, doc.Tags) {
+				continue
+			}
+			if _, err := col.UpdateOne(ctx, bson.M{"_id": doc.ID}, bson.M{"$set": bson.M{"tags": tags}}); err != nil {
+				log.Println("Error migrando los tags de", doc.ID.Hex(), ":", err)
+				continue
+			}
+			migrados++
+		}
+		cur.Close(ctx)
+	}
+
+	usos := config.GetCollection("tag_usos")
+	cur, err := usos.Find(ctx, bson.M{"tag": tagSinNormalizar})
+	if err != nil {
+		log.Println("Error buscando usos de tags para migrar:", err)
+		return
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		var uso models.UsoTag
+		if err := cur.Decode(&uso); err != nil {
+			continue
+		}
+		tag := NormalizarTag(uso.Tag)
+		switch {
+		case tag == uso.Tag:
+			continue
+		case tag == "":
+			_, err = usos.DeleteOne(ctx, bson.M{"_id": uso.ID})
+		default:
+			_, err = usos.UpdateOne(ctx, bson.M{"_id": uso.ID}, bson.M{"$set": bson.M{"tag": tag}})
+		}
+		if err != nil {
+			log.Println("Error migrando el uso de tag", uso.ID.Hex(), ":", err)
+		}
+	}
+	if migrados > 0 {
+		log.Println("Tags normalizados en", migrados, "posts")
+	}
+}
@@ -0,0 +1,399 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"post-service/config"
+	"post-service/models"
+	"post-service/storage"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/buckket/go-blurhash"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // registra el decodificador webp
+)
+
+// anchosVariantes son los anchos en píxeles de las variantes responsivas. Nunca se
+// amplía una imagen: si es más angosta se agrega una variante de su propio ancho.
+var anchosVariantes = []int{320, 640, 1080, 1920}
+
+const (
+	calidadVariantes = 80
+	anchoBlurhash    = 32 // la imagen se reduce antes de calcular el blurhash
+	limiteCwebp      = time.Minute
+
+	// maxPixelesImagen limita lo que se decodifica: un PNG de pocos MB puede
+	// declarar 30000×30000 píxeles y ocupar gigabytes al decodificarlo.
+	maxPixelesImagen = 50_000_000
+)
+
+// cwebp devuelve la ruta del binario cwebp (configurable con CWEBP_PATH) o "" si no
+// está instalado, en cuyo caso solo se generan variantes JPEG.
+func cwebp() string {
+	return binario("CWEBP_PATH", "cwebp")
+}
+
+// ProcesarImagen genera las variantes WebP/JPEG y el blurhash de un adjunto de
+// imagen. Las variantes se reencodifican desde los píxeles, así que no conservan
+// EXIF ni otros metadatos (ubicación, cámara, etc.).
+func ProcesarImagen(adjunto *models.Adjunto) error {
+	ctx := context.TODO()
+
+	// El mismo archivo ya procesado para otro adjunto se reutiliza
+	var previo models.Adjunto
+	err := config.GetCollection("adjuntos").FindOne(ctx, bson.M{
+		"hash":          adjunto.Hash,
+		"procesamiento": models.ProcesamientoListo,
+	}).Decode(&previo)
+	if err == nil {
+		return ActualizarAdjunto(adjunto.ID, camposProcesados(&previo))
+	}
+
+	obj, err := storage.Actual.Get(ctx, ClaveArchivo(adjunto.Archivo))
+	if err != nil {
+		return err
+	}
+	datos, err := io.ReadAll(obj)
+	obj.Close()
+	if err != nil {
+		return err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(datos))
+	if err != nil {
+		return fmt.Errorf("decodificando la imagen: %w", err)
+	}
+	if demasiadosPixeles(cfg.Width, cfg.Height) {
+		return fmt.Errorf("la imagen tiene demasiados píxeles (%dx%d)", cfg.Width, cfg.Height)
+	}
+	img, formato, err := image.Decode(bytes.NewReader(datos))
+	if err != nil {
+		return fmt.Errorf("decodificando la imagen: %w", err)
+	}
+	if formato == "jpeg" {
+		img = aplicarOrientacion(img, OrientacionEXIF(datos))
+	}
+	ancho, alto := img.Bounds().Dx(), img.Bounds().Dy()
+	if ancho == 0 || alto == 0 {
+		return fmt.Errorf("imagen vacía")
+	}
+
+	resultado := models.Adjunto{Ancho: ancho, Alto: alto}
+	resultado.Blurhash, err = blurhash.Encode(4, 3, redimensionar(img, anchoBlurhash))
+	if err != nil {
+		return fmt.Errorf("calculando el blurhash: %w", err)
+	}
+
+	var srcWebP, srcJPEG []string
+	for _, w := range anchosVariantes {
+		if w > ancho {
+			w = ancho
+		}
+		variante := redimensionar(img, w)
+		h := variante.Bounds().Dy()
+
+		jpg, err := codificarJPEG(variante)
+		if err != nil {
+			return err
+		}
+		v, err := guardarVariante(adjunto.Hash, w, h, "jpeg", ".jpg", jpg)
+		if err != nil {
+			return err
+		}
+		resultado.Variantes = append(resultado.Variantes, v)
+		resultado.Derivados = append(resultado.Derivados, ArchivoVariante(adjunto.Hash, w, ".jpg"))
+		srcJPEG = append(srcJPEG, v.URL+" "+strconv.Itoa(w)+"w")
+
+		if cwebp() != "" {
+			webp, err := codificarWebP(variante)
+			if err != nil {
+				return err
+			}
+			v, err := guardarVariante(adjunto.Hash, w, h, "webp", ".webp", webp)
+			if err != nil {
+				return err
+			}
+			resultado.Variantes = append(resultado.Variantes, v)
+			resultado.Derivados = append(resultado.Derivados, ArchivoVariante(adjunto.Hash, w, ".webp"))
+			srcWebP = append(srcWebP, v.URL+" "+strconv.Itoa(w)+"w")
+		}
+		if w == ancho {
+			break
+		}
+	}
+	resultado.SrcsetJPEG = strings.Join(srcJPEG, ", ")
+	resultado.SrcsetWebP = strings.Join(srcWebP, ", ")
+	resultado.Procesamiento = models.ProcesamientoListo
+
+	return ActualizarAdjunto(adjunto.ID, camposProcesados(&resultado))
+}
+
+// camposProcesados son los campos que ProcesarImagen completa en el adjunto.
+func camposProcesados(a *models.Adjunto) bson.M {
+	return bson.M{
+		"ancho":         a.Ancho,
+		"alto":          a.Alto,
+		"variantes":     a.Variantes,
+		"srcsetWebp":    a.SrcsetWebP,
+		"srcsetJpeg":    a.SrcsetJPEG,
+		"blurhash":      a.Blurhash,
+		"derivados":     a.Derivados,
+		"procesamiento": models.ProcesamientoListo,
+	}
+}
+
+// ArchivoVariante es el nombre de la variante de ancho w de un archivo.
+func ArchivoVariante(hash string, ancho int, ext string) string {
+	return hash + "_" + strconv.Itoa(ancho) + ext
+}
+
+// guardarVariante sube una variante junto al archivo original.
+func guardarVariante(hash string, ancho, alto int, formato, ext string, datos []byte) (models.Variante, error) {
+	archivo := ArchivoVariante(hash, ancho, ext)
+	err := storage.Actual.Put(context.TODO(), ClaveArchivo(archivo), bytes.NewReader(datos), int64(len(datos)), "image/"+formato)
+	if err != nil {
+		return models.Variante{}, err
+	}
+	return models.Variante{
+		Ancho:   ancho,
+		Alto:    alto,
+		Formato: formato,
+		URL:     "/media/" + archivo,
+		Tamano:  int64(len(datos)),
+	}, nil
+}
+
+// redimensionar escala img al ancho indicado manteniendo la proporción.
+func redimensionar(img image.Image, ancho int) *image.NRGBA {
+	b := img.Bounds()
+	alto := max(1, b.Dy()*ancho/b.Dx())
+	dst := image.NewNRGBA(image.Rect(0, 0, ancho, alto))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// codificarJPEG encodifica img sobre fondo blanco (JPEG no tiene transparencia).
+func codificarJPEG(img image.Image) ([]byte, error) {
+	fondo := image.NewRGBA(img.Bounds())
+	draw.Draw(fondo, fondo.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(fondo, fondo.Bounds(), img, img.Bounds().Min, draw.Over)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, fondo, &jpeg.Options{Quality: calidadVariantes}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// codificarWebP convierte img a WebP con cwebp (Go no trae un encoder WebP).
+func codificarWebP(img image.Image) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "webp-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	entrada, salida := filepath.Join(dir, "entrada.png"), filepath.Join(dir, "salida.webp")
+	f, err := os.Create(entrada)
+	if err != nil {
+		return nil, err
+	}
+	err = (&png.Encoder{CompressionLevel: png.BestSpeed}).Encode(f, img)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancelar := context.WithTimeout(context.Background(), limiteCwebp)
+	defer cancelar()
+	cmd := exec.CommandContext(ctx, cwebp(), "-quiet", "-q", strconv.Itoa(calidadVariantes), "-metadata", "none", entrada, "-o", salida)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("cwebp: %v: %s", err, out)
+	}
+	return os.ReadFile(salida)
+}
+
+// demasiadosPixeles indica si una imagen de ancho×alto supera maxPixelesImagen.
+func demasiadosPixeles(ancho, alto int) bool {
+	return int64(ancho)*int64(alto) > maxPixelesImagen
+}
+
+// quitarMetadatosJPEG devuelve el JPEG sin los segmentos con metadatos personales:
+// APP1 (EXIF, con la ubicación GPS, y XMP), APP13 (IPTC) y los comentarios. La
+// orientación EXIF se conserva en un APP1 mínimo para que el original se siga
+// viendo derecho; los datos de la imagen se copian tal cual. Un JPEG que no se
+// puede recorrer se rechaza con ErrTipoNoPermitido.
+func quitarMetadatosJPEG(datos []byte) ([]byte, error) {
+	if len(datos) < 4 || datos[0] != 0xFF || datos[1] != 0xD8 {
+		return nil, ErrTipoNoPermitido
+	}
+	limpio := make([]byte, 0, len(datos))
+	limpio = append(limpio, 0xFF, 0xD8)
+	var orientacion []byte // se inserta después del APP0 (JFIF), si lo hay
+	if o := OrientacionEXIF(datos); o != 1 {
+		orientacion = app1Orientacion(o)
+	}
+	for i := 2; i+4 <= len(datos); {
+		if datos[i] != 0xFF {
+			return nil, ErrTipoNoPermitido
+		}
+		marcador := datos[i+1]
+		if marcador != 0xFF && marcador != 0xE0 {
+			limpio, orientacion = append(limpio, orientacion...), nil
+		}
+		switch {
+		case marcador == 0xFF: // relleno
+			i++
+			continue
+		case marcador == 0x01 || (marcador >= 0xD0 && marcador <= 0xD7): // sin longitud
+			limpio = append(limpio, datos[i:i+2]...)
+			i += 2
+			continue
+		case marcador == 0xDA: // empiezan los datos de imagen
+			return append(limpio, datos[i:]...), nil
+		case marcador == 0xD9:
+			return nil, ErrTipoNoPermitido
+		}
+		largo := int(binary.BigEndian.Uint16(datos[i+2:]))
+		if largo < 2 || i+2+largo > len(datos) {
+			return nil, ErrTipoNoPermitido
+		}
+		if marcador != 0xE1 && marcador != 0xED && marcador != 0xFE {
+			limpio = append(limpio, datos[i:i+2+largo]...)
+		}
+		i += 2 + largo
+	}
+	return nil, ErrTipoNoPermitido
+}
+
+// app1Orientacion arma un segmento APP1 Exif que solo contiene la orientación.
+func app1Orientacion(o int) []byte {
+	return []byte{
+		0xFF, 0xE1, 0, 34, // marcador y largo
+		'E', 'x', 'i', 'f', 0, 0,
+		'M', 'M', 0, 42, 0, 0, 0, 8, // cabecera TIFF big endian, IFD en 8
+		0, 1, // una entrada
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, byte(o), 0, 0, // Orientation, SHORT, 1 valor
+		0, 0, 0, 0, // sin más IFDs
+	}
+}
+
+// OrientacionEXIF devuelve la orientación (1-8) guardada en el EXIF de un JPEG, o 1
+// si no tiene.
+func OrientacionEXIF(datos []byte) int {
+	if len(datos) < 4 || datos[0] != 0xFF || datos[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(datos); {
+		if datos[i] != 0xFF {
+			return 1
+		}
+		marcador := datos[i+1]
+		switch {
+		case marcador == 0xFF: // relleno
+			i++
+			continue
+		case marcador == 0x01 || (marcador >= 0xD0 && marcador <= 0xD7): // sin longitud
+			i += 2
+			continue
+		case marcador == 0xDA || marcador == 0xD9: // empiezan los datos de imagen
+			return 1
+		}
+		largo := int(binary.BigEndian.Uint16(datos[i+2:]))
+		if largo < 2 || i+2+largo > len(datos) {
+			return 1
+		}
+		if marcador == 0xE1 {
+			if o := orientacionTIFF(datos[i+4 : i+2+largo]); o != 0 {
+				return o
+			}
+		}
+		i += 2 + largo
+	}
+	return 1
+}
+
+// orientacionTIFF lee la etiqueta 0x0112 del primer IFD de un segmento APP1 Exif.
+func orientacionTIFF(seg []byte) int {
+	if len(seg) < 14 || string(seg[:6]) != "Exif\x00\x00" {
+		return 0
+	}
+	tiff := seg[6:]
+	var orden binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		orden = binary.LittleEndian
+	case "MM":
+		orden = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(orden.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	n := int(orden.Uint16(tiff[ifd:]))
+	for k := range n {
+		e := ifd + 2 + 12*k
+		if e+12 > len(tiff) {
+			return 0
+		}
+		if orden.Uint16(tiff[e:]) == 0x0112 {
+			if o := int(orden.Uint16(tiff[e+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// aplicarOrientacion rota o refleja img para que se vea como indica la orientación
+// EXIF, ya que las variantes se guardan sin ese dato.
+func aplicarOrientacion(img image.Image, orientacion int) image.Image {
+	if orientacion <= 1 || orientacion > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientacion >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientacion {
+			case 2: // espejo horizontal
+				dx, dy = w-1-x, y
+			case 3: // 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // espejo vertical
+				dx, dy = x, h-1-y
+			case 5: // transpuesta
+				dx, dy = y, x
+			case 6: // 90° horario
+				dx, dy = h-1-y, x
+			case 7: // transversa
+				dx, dy = h-1-y, w-1-x
+			case 8: // 90° antihorario
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+// jpegCon arma un JPEG mínimo (SOI + segmentos + SOS + EOI); no es decodificable,
+// pero basta para los lectores de segmentos.
+func jpegCon(segmentos ...[]byte) []byte {
+	datos := []byte{0xFF, 0xD8}
+	for _, s := range segmentos {
+		datos = append(datos, s...)
+	}
+	return append(datos, 0xFF, 0xDA, 0, 2, 0xAB, 0xCD, 0xFF, 0xD9)
+}
+
+// segmento arma un segmento con marcador y contenido, calculando el largo.
+func segmento(marcador byte, contenido []byte) []byte {
+	largo := len(contenido) + 2
+	return append([]byte{0xFF, marcador, byte(largo >> 8), byte(largo)}, contenido...)
+}
+
+// exifCon arma el contenido de un APP1 Exif con una sola entrada en el primer IFD.
+func exifCon(orden string, etiqueta, valor uint16) []byte {
+	e := func(v uint16) []byte {
+		if orden == "II" {
+			return []byte{byte(v), byte(v >> 8)}
+		}
+		return []byte{byte(v >> 8), byte(v)}
+	}
+	tiff := append([]byte(orden), e(42)...)
+	if orden == "II" {
+		tiff = append(tiff, 8, 0, 0, 0)
+	} else {
+		tiff = append(tiff, 0, 0, 0, 8)
+	}
+	tiff = append(tiff, e(1)...)
+	tiff = append(tiff, e(etiqueta)...)
+	tiff = append(tiff, e(3)...)
+	if orden == "II" {
+		tiff = append(tiff, 1, 0, 0, 0)
+	} else {
+		tiff = append(tiff, 0, 0, 0, 1)
+	}
+	tiff = append(tiff, e(valor)...)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	return append([]byte("Exif\x00\x00"), tiff...)
+}
+
+func TestOrientacionEXIF(t *testing.T) {
+	app0 := segmento(0xE0, []byte("JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00"))
+	casos := []struct {
+		nombre string
+		datos  []byte
+		quiere int
+	}{
+		{"vacío", nil, 1},
+		{"no es JPEG", []byte("\x89PNG\r\n\x1a\n"), 1},
+		{"sin EXIF", jpegCon(app0), 1},
+		{"big endian", jpegCon(segmento(0xE1, exifCon("MM", 0x0112, 6))), 6},
+		{"little endian", jpegCon(app0, segmento(0xE1, exifCon("II", 0x0112, 8))), 8},
+		{"con relleno antes del marcador", jpegCon([]byte{0xFF}, segmento(0xE1, exifCon("MM", 0x0112, 3))), 3},
+		{"orientación fuera de rango", jpegCon(segmento(0xE1, exifCon("MM", 0x0112, 9))), 1},
+		{"otra etiqueta", jpegCon(segmento(0xE1, exifCon("MM", 0x010F, 6))), 1},
+		{"APP1 XMP", jpegCon(segmento(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00<x/>"))), 1},
+		{"orden de bytes inválido", jpegCon(segmento(0xE1, exifCon("XX", 0x0112, 6))), 1},
+		{"APP1 recortado", jpegCon(segmento(0xE1, exifCon("MM", 0x0112, 6)[:12])), 1},
+		{"largo mayor que el archivo", append([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF}, exifCon("MM", 0x0112, 6)...), 1},
+		{"largo menor que 2", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0, 1, 0, 0}, 1},
+		{"EXIF después de SOS", append(jpegCon(), segmento(0xE1, exifCon("MM", 0x0112, 6))...), 1},
+		{
+			"IFD fuera del segmento",
+			jpegCon(segmento(0xE1, []byte("Exif\x00\x00MM\x00\x2a\x7f\xff\xff\xff\x00\x00"))), 1,
+		},
+		{
+			"más entradas que bytes",
+			jpegCon(segmento(0xE1, []byte("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08\xff\xff\x01\x12"))), 1,
+		},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			if got := OrientacionEXIF(c.datos); got != c.quiere {
+				t.Errorf("OrientacionEXIF = %d, quiere %d", got, c.quiere)
+			}
+		})
+	}
+}
+
+func TestQuitarMetadatosJPEG(t *testing.T) {
+	app0 := segmento(0xE0, []byte("JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00"))
+	icc := segmento(0xE2, []byte("ICC_PROFILE\x00\x01\x01"))
+	gps := segmento(0xE1, exifCon("MM", 0x8825, 26)) // puntero al IFD GPS
+	xmp := segmento(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00<x/>"))
+	iptc := segmento(0xED, []byte("Photoshop 3.0\x00"))
+	comentario := segmento(0xFE, []byte("tomada en casa"))
+
+	casos := []struct {
+		nombre string
+		datos  []byte
+		quiere []byte
+	}{
+		{"sin metadatos", jpegCon(app0, icc), jpegCon(app0, icc)},
+		{"quita EXIF, XMP, IPTC y comentarios", jpegCon(app0, gps, xmp, iptc, comentario, icc), jpegCon(app0, icc)},
+		{
+			"conserva la orientación después del APP0",
+			jpegCon(app0, segmento(0xE1, exifCon("II", 0x0112, 6)), icc),
+			jpegCon(app0, app1Orientacion(6), icc),
+		},
+		{
+			"orientación sin APP0",
+			jpegCon(segmento(0xE1, exifCon("MM", 0x0112, 8))),
+			jpegCon(app1Orientacion(8)),
+		},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			got, err := quitarMetadatosJPEG(c.datos)
+			if err != nil || !bytes.Equal(got, c.quiere) {
+				t.Errorf("quitarMetadatosJPEG = % x, %v; quiere % x", got, err, c.quiere)
+			}
+		})
+	}
+
+	invalidos := map[string][]byte{
+		"vacío":               nil,
+		"no es JPEG":          []byte("GIF89a"),
+		"sin SOS":             {0xFF, 0xD8, 0xFF, 0xD9},
+		"basura entre marcas": {0xFF, 0xD8, 0x00, 0x00, 0xFF, 0xDA},
+		"segmento recortado":  {0xFF, 0xD8, 0xFF, 0xE1, 0x10, 0x00, 'E', 'x'},
+		"largo menor que 2":   {0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x01, 0xFF, 0xDA},
+		"termina sin imagen":  jpegCon(app0)[:len(app0)+2],
+	}
+	for nombre, datos := range invalidos {
+		t.Run(nombre, func(t *testing.T) {
+			if _, err := quitarMetadatosJPEG(datos); err != ErrTipoNoPermitido {
+				t.Errorf("quitarMetadatosJPEG = %v, quiere ErrTipoNoPermitido", err)
+			}
+		})
+	}
+}
+
+func TestQuitarMetadatosJPEGSigueDecodificando(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 4)), nil); err != nil {
+		t.Fatal(err)
+	}
+	original := buf.Bytes()
+	conExif := append([]byte{0xFF, 0xD8}, segmento(0xE1, exifCon("MM", 0x0112, 6))...)
+	conExif = append(conExif, original[2:]...)
+
+	limpio, err := quitarMetadatosJPEG(conExif)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := OrientacionEXIF(limpio); got != 6 {
+		t.Errorf("orientación tras limpiar = %d, quiere 6", got)
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(limpio))
+	if err != nil || cfg.Width != 8 || cfg.Height != 4 {
+		t.Errorf("DecodeConfig = %+v, %v", cfg, err)
+	}
+}
+
+func TestDemasiadosPixeles(t *testing.T) {
+	casos := []struct {
+		ancho, alto int
+		quiere      bool
+	}{
+		{4000, 3000, false},
+		{10000, 5000, false},
+		{10000, 5001, true},
+		{30000, 30000, true},
+		{1 << 31, 1 << 31, true}, // no desborda
+	}
+	for _, c := range casos {
+		if got := demasiadosPixeles(c.ancho, c.alto); got != c.quiere {
+			t.Errorf("demasiadosPixeles(%d, %d) = %v, quiere %v", c.ancho, c.alto, got, c.quiere)
+		}
+	}
+}
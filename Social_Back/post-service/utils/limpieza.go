@@ -0,0 +1,228 @@
+package utils
+
+import (
+	"context"
+	"log"
+	"os"
+	"post-service/config"
+	"post-service/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	intervaloLimpiezaDefecto = time.Hour
+	loteLimpieza             = 500                // documentos que revisa cada paso por pasada
+	purgaInterrumpida        = 10 * time.Minute   // una purga más vieja que esto se retoma
+	adjuntoSinUsar           = 24 * time.Hour     // adjuntos que nunca se usaron en un post
+	trabajoTerminado         = 7 * 24 * time.Hour // historial de trabajos de media
+)
+
+// IniciarLimpieza arranca el barrido periódico de datos huérfanos (cada
+// LIMPIEZA_INTERVALO, por defecto una hora). Todos los pasos son idempotentes,
+// así que no importa si corre en varias instancias a la vez.
+func IniciarLimpieza() {
+	intervalo, err := time.ParseDuration(os.Getenv("LIMPIEZA_INTERVALO"))
+	if err != nil || intervalo <= 0 {
+		intervalo = intervaloLimpiezaDefecto
+	}
+	go func() {
+		for {
+			Limpiar()
+			time.Sleep(intervalo)
+		}
+	}()
+}
+
+// Limpiar hace una pasada del barrido: retoma purgas interrumpidas, purga los
+// posts cuyo plazo de papelera venció o cuyo autor ya no existe y borra lo que
+// quedó colgando de eliminaciones anteriores.
+func Limpiar() {
+	ctx := context.TODO()
+	hace := func(d time.Duration) time.Time { return time.Now().Add(-d) }
+
+	// 1. Purgas que se cortaron a mitad de camino
+	var purgas []purgaPost
+	cur, err := config.GetCollection("purgas_posts").Find(ctx, bson.M{"fecha": bson.M{"$lt": hace(purgaInterrumpida)}})
+	if err == nil {
+		err = cur.All(ctx, &purgas)
+	}
+	registrarErrorLimpieza("purgas interrumpidas", err)
+	for _, p := range purgas {
+		registrarErrorLimpieza("purga interrumpida", continuarPurga(p.ID))
+	}
+
+	// 2. Posts cuyo plazo para restaurarlos venció
+	vencidos := bson.M{"eliminadoEn": bson.M{"$lt": time.Now().AddDate(0, 0, -DiasPapelera())}}
+	purgarPostsDe(ctx, "posts_papelera", mongo.Pipeline{{{Key: "$match", Value: vencidos}}})
+
+	// 3. Posts de usuarios que ya no existen. Si "users" está vacía (p. ej. una base
+	// mal configurada) no se toca nada para no purgar todos los posts
+	usuarios, err := config.GetCollection("users").EstimatedDocumentCount(ctx)
+	registrarErrorLimpieza("users", err)
+	hayUsuarios := err == nil && usuarios > 0
+	if hayUsuarios {
+		for _, coleccion := range []string{"posts", "posts_papelera"} {
+			purgarPostsDe(ctx, coleccion, sinReferencia("autorId", "_id", "users"))
+		}
+	}
+
+	// 4. Datos que apuntan a un post que ya no existe
+	for _, coleccion := range coleccionesDelPost {
+		ids := idsHuerfanos(ctx, coleccion, "postId", "posts", "posts_papelera")
+		if len(ids) > 0 {
+			res, err := config.GetCollection(coleccion).DeleteMany(ctx, bson.M{"postId": bson.M{"$in": ids}})
+			registrarErrorLimpieza(coleccion, err)
+			if err == nil && res.DeletedCount > 0 {
+				log.Println("Limpieza:", res.DeletedCount, "documentos huérfanos en", coleccion)
+			}
+		}
+	}
+	purgarPostsDe(ctx, "posts", sinReferencia("repostDe", "_id", "posts", "posts_papelera"))
+	if ids := idsHuerfanos(ctx, "reacciones", "comentarioId", "comentarios"); len(ids) > 0 {
+		_, err := config.GetCollection("reacciones").DeleteMany(ctx, bson.M{"comentarioId": bson.M{"$in": ids}})
+		registrarErrorLimpieza("reacciones a comentarios", err)
+	}
+
+	// 5. Rastros de usuarios que ya no existen
+	if hayUsuarios {
+		limpiarUsuariosEliminados(ctx)
+	}
+
+	// 6. Archivos: subidas nunca confirmadas o nunca usadas en un post
+	var adjuntos []models.Adjunto
+	pipeline := append(mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"fecha": bson.M{"$lt": hace(adjuntoSinUsar)}}}},
+	}, sinReferencia("_id", "adjuntos._id", "posts", "posts_papelera")...)
+	cur, err = config.GetCollection("adjuntos").Aggregate(ctx, append(pipeline, bson.D{{Key: "$limit", Value: loteLimpieza}}))
+	if err == nil {
+		err = cur.All(ctx, &adjuntos)
+	}
+	registrarErrorLimpieza("adjuntos sin usar", err)
+	for _, a := range adjuntos {
+		registrarErrorLimpieza("adjunto "+a.ID.Hex(), liberarAdjunto(ctx, a.ID))
+	}
+
+	// 7. Trabajos de media terminados o de adjuntos borrados
+	_, err = config.GetCollection("trabajos_media").DeleteMany(ctx, bson.M{
+		"estado": bson.M{"$in": []string{"listo", "fallido"}},
+		"fecha":  bson.M{"$lt": hace(trabajoTerminado)},
+	})
+	registrarErrorLimpieza("trabajos de media", err)
+	if ids := idsHuerfanos(ctx, "trabajos_media", "adjuntoId", "adjuntos"); len(ids) > 0 {
+		_, err := config.GetCollection("trabajos_media").DeleteMany(ctx, bson.M{"adjuntoId": bson.M{"$in": ids}})
+		registrarErrorLimpieza("trabajos de media", err)
+	}
+}
+
+// limpiarUsuariosEliminados quita las reacciones (ajustando los contadores),
+// seguimientos, notificaciones y guardados de usuarios que ya no existen, y deja sus
+// comentarios como eliminados para no romper los hilos.
+func limpiarUsuariosEliminados(ctx context.Context) {
+	var reacciones []models.Reaccion
+	cur, err := config.GetCollection("reacciones").Aggregate(ctx, append(
+		sinReferencia("usuarioId", "_id", "users"), bson.D{{Key: "$limit", Value: loteLimpieza}},
+	))
+	if err == nil {
+		err = cur.All(ctx, &reacciones)
+	}
+	registrarErrorLimpieza("reacciones de usuarios eliminados", err)
+	for _, r := range reacciones {
+		_, err := QuitarReaccion(r.PostID, r.ComentarioID, r.UsuarioID)
+		registrarErrorLimpieza("reacción "+r.ID.Hex(), err)
+	}
+
+	if ids := idsHuerfanos(ctx, "comentarios", "autorId", "users"); len(ids) > 0 {
+		_, err := config.GetCollection("comentarios").UpdateMany(ctx,
+			bson.M{"autorId": bson.M{"$in": ids}, "eliminado": bson.M{"$ne": true}},
+			bson.M{"$set": bson.M{"eliminado": true, "eliminadoPor": "cuenta_eliminada", "contenido": ""}},
+		)
+		registrarErrorLimpieza("comentarios de usuarios eliminados", err)
+	}
+
+	for _, ref := range []struct{ coleccion, campo string }{
+		{"follows", "seguidorId"}, {"follows", "seguidoId"},
+		{"follow_requests", "seguidorId"}, {"follow_requests", "seguidoId"},
+		{"notificaciones", "para"}, {"notificaciones", "de"},
+		{"guardados", "usuarioId"}, {"colecciones_guardados", "usuarioId"},
+		{"grupos", "duenoId"},
+	} {
+		if ids := idsHuerfanos(ctx, ref.coleccion, ref.campo, "users"); len(ids) > 0 {
+			_, err := config.GetCollection(ref.coleccion).DeleteMany(ctx, bson.M{ref.campo: bson.M{"$in": ids}})
+			registrarErrorLimpieza(ref.coleccion, err)
+		}
+	}
+}
+
+// purgarPostsDe purga los posts de coleccion que devuelve el pipeline.
+func purgarPostsDe(ctx context.Context, coleccion string, pipeline mongo.Pipeline) {
+	var posts []models.Post
+	cur, err := config.GetCollection(coleccion).Aggregate(ctx, append(pipeline, bson.D{{Key: "$limit", Value: loteLimpieza}}))
+	if err == nil {
+		err = cur.All(ctx, &posts)
+	}
+	registrarErrorLimpieza(coleccion, err)
+	for _, p := range posts {
+		registrarErrorLimpieza("post "+p.ID.Hex(), PurgarPost(p))
+	}
+	if len(posts) > 0 {
+		log.Println("Limpieza:", len(posts), "posts purgados de", coleccion)
+	}
+}
+
+// sinReferencia selecciona los documentos cuyo campo local no aparece como
+// campo foráneo en ninguna de las colecciones destino.
+func sinReferencia(local, foraneo string, destinos ...string) mongo.Pipeline {
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{local: bson.M{"$ne": nil}}}}}
+	for _, destino := range destinos {
+		pipeline = append(pipeline,
+			bson.D{{Key: "$lookup", Value: bson.M{
+				"from":         destino,
+				"localField":   local,
+				"foreignField": foraneo,
+				"pipeline":     bson.A{bson.M{"$limit": 1}, bson.M{"$project": bson.M{"_id": 1}}},
+				"as":           "referencias",
+			}}},
+			bson.D{{Key: "$match", Value: bson.M{"referencias": bson.M{"$size": 0}}}},
+		)
+	}
+	return append(pipeline, bson.D{{Key: "$project", Value: bson.M{"referencias": 0}}})
+}
+
+// idsHuerfanos devuelve hasta loteLimpieza valores distintos de campo en coleccion
+// que no existen como _id en ninguna de las colecciones destino.
+func idsHuerfanos(ctx context.Context, coleccion, campo string, destinos ...string) []primitive.ObjectID {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{campo: bson.M{"$ne": nil}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$" + campo}}},
+	}
+	pipeline = append(pipeline, sinReferencia("_id", "_id", destinos...)...)
+	pipeline = append(pipeline, bson.D{{Key: "$limit", Value: loteLimpieza}})
+	cur, err := config.GetCollection(coleccion).Aggregate(ctx, pipeline)
+	if err != nil {
+		registrarErrorLimpieza(coleccion, err)
+		return nil
+	}
+	var filas []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cur.All(ctx, &filas); err != nil {
+		registrarErrorLimpieza(coleccion, err)
+		return nil
+	}
+	ids := make([]primitive.ObjectID, 0, len(filas))
+	for _, f := range filas {
+		ids = append(ids, f.ID)
+	}
+	return ids
+}
+
+// registrarErrorLimpieza deja en el log un error de la limpieza sin interrumpirla.
+func registrarErrorLimpieza(que string, err error) {
+	if err != nil {
+		log.Println("Error en la limpieza (", que, "):", err)
+	}
+}
@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"context"
+	"post-service/config"
+	"post-service/models"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxMencionesPorTexto limita cuántos usuarios se pueden mencionar (y notificar) de una vez.
+const maxMencionesPorTexto = 20
+
+// patronMencion encuentra @username cuando la @ no va pegada a otra palabra (así los
+// correos como ana@uni.edu no cuentan como menciones).
+var patronMencion = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.@])@([\p{L}\p{N}_.]+)`)
+
+// ColacionUsername compara usernames sin distinguir mayúsculas.
+var ColacionUsername = &options.Collation{Locale: "es", Strength: 2}
+
+// ResolverMenciones busca los @username de texto en la colección "users" y devuelve
+// las menciones válidas con sus posiciones. Se descartan los usuarios inexistentes
+// y los que tienen un bloqueo con autorID.
+func ResolverMenciones(texto string, autorID primitive.ObjectID) ([]models.Mencion, error) {
+	type candidato struct {
+		username    string
+		inicio, fin int
+	}
+	var candidatos []candidato
+	var usernames []string
+	vistos := map[string]bool{}
+	for _, idx := range patronMencion.FindAllStringSubmatchIndex(texto, -1) {
+		// idx[2]:idx[3] es el username; la @ está justo antes
+		username := strings.TrimRight(texto[idx[2]:idx[3]], ".")
+		if username == "" {
+			continue
+		}
+		inicio := utf8.RuneCountInString(texto[:idx[2]-1])
+		candidatos = append(candidatos, candidato{username, inicio, inicio + 1 + utf8.RuneCountInString(username)})
+		if clave := strings.ToLower(username); !vistos[clave] {
+			if len(usernames) == maxMencionesPorTexto {
+				break
+			}
+			vistos[clave] = true
+			usernames = append(usernames, username)
+		}
+	}
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+
+	filtro := bson.M{"username": bson.M{"$in": usernames}}
+	bloqueados, err := UsuariosBloqueados(autorID)
+	if err != nil {
+		return nil, err
+	}
+	if len(bloqueados) > 0 {
+		filtro["_id"] = bson.M{"$nin": bloqueados}
+	}
+	opts := options.Find().
+		SetCollation(ColacionUsername).
+		SetProjection(bson.M{"username": 1})
+	cur, err := config.GetCollection("users").Find(context.TODO(), filtro, opts)
+	if err != nil {
+		return nil, err
+	}
+	var usuarios []models.UsuarioPublico
+	if err := cur.All(context.TODO(), &usuarios); err != nil {
+		return nil, err
+	}
+	porNombre := make(map[string]models.UsuarioPublico, len(usuarios))
+	for _, u := range usuarios {
+		porNombre[strings.ToLower(u.Username)] = u
+	}
+
+	menciones := []models.Mencion{}
+	for _, cand := range candidatos {
+		if u, ok := porNombre[strings.ToLower(cand.username)]; ok {
+			menciones = append(menciones, models.Mencion{
+				UsuarioID: u.ID,
+				Username:  u.Username,
+				Inicio:    cand.inicio,
+				Fin:       cand.fin,
+			})
+		}
+	}
+	return menciones, nil
+}
+
+// NotificarMenciones envía una notificación "mention" a cada usuario mencionado
+// (una sola vez por usuario y nunca al propio autor). Se omiten los de excluir,
+// por ejemplo quienes ya estaban mencionados antes de una edición.
+func NotificarMenciones(menciones []models.Mencion, autorID, postID primitive.ObjectID, mensaje string, excluir []models.Mencion) {
+	notificados := map[primitive.ObjectID]bool{autorID: true}
+	for _, m := range excluir {
+		notificados[m.UsuarioID] = true
+	}
+	for _, m := range menciones {
+		if notificados[m.UsuarioID] {
+			continue
+		}
+		notificados[m.UsuarioID] = true
+		CrearNotificacion("mention", autorID, m.UsuarioID, mensaje, &postID)
+	}
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"context"
+	"post-service/config"
+	"post-service/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// rolesModeracion son los roles de "users" que pueden moderar cualquier contenido.
+var rolesModeracion = map[string]bool{"admin": true, "moderador": true}
+
+// EsModerador indica si el usuario tiene un rol de moderación.
+func EsModerador(usuarioID primitive.ObjectID) (bool, error) {
+	var usuario models.UsuarioPublico
+	err := config.GetCollection("users").FindOne(context.TODO(),
+		bson.M{"_id": usuarioID},
+		options.FindOne().SetProjection(bson.M{"rol": 1}),
+	).Decode(&usuario)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return rolesModeracion[usuario.Rol], nil
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrCursorInvalido se devuelve cuando el cursor recibido no puede decodificarse.
+var ErrCursorInvalido = errors.New("cursor inválido")
+
+// Cursor marca la posición del último documento entregado en un listado
+// ordenado por fecha descendente y _id descendente (keyset pagination).
+// En los listados ordenados por un contador se usa Valor en lugar de Fecha y
+// Orden recuerda qué orden generó el cursor.
+type Cursor struct {
+	Fecha time.Time          `json:"f,omitempty"`
+	Valor int64              `json:"v,omitempty"`
+	Orden string             `json:"o,omitempty"`
+	ID    primitive.ObjectID `json:"i"`
+}
+
+// CodificarCursor genera un cursor opaco para el cliente a partir de la fecha e _id del último documento.
+func CodificarCursor(fecha time.Time, id primitive.ObjectID) string {
+	return codificar(Cursor{Fecha: fecha, ID: id})
+}
+
+// CodificarCursorFecha es CodificarCursor para un listado que admite más de un
+// orden por fecha: Orden evita que el cursor de uno se use en otro.
+func CodificarCursorFecha(orden string, fecha time.Time, id primitive.ObjectID) string {
+	return codificar(Cursor{Fecha: fecha, Orden: orden, ID: id})
+}
+
+// CodificarCursorConteo genera un cursor opaco para un listado ordenado por un
+// contador (likes, comentarios...) descendente y _id descendente.
+func CodificarCursorConteo(orden string, valor int64, id primitive.ObjectID) string {
+	return codificar(Cursor{Valor: valor, Orden: orden, ID: id})
+}
+
+func codificar(cur Cursor) string {
+	raw, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodificarCursor interpreta un cursor generado por CodificarCursor,
+// CodificarCursorFecha o CodificarCursorConteo.
+// Un cursor vacío significa "primera página" y devuelve nil sin error.
+func DecodificarCursor(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrCursorInvalido
+	}
+	var cur Cursor
+	if err := json.Unmarshal(raw, &cur); err != nil || cur.ID.IsZero() {
+		return nil, ErrCursorInvalido
+	}
+	return &cur, nil
+}
+
+// FiltroDespuesDe devuelve la condición que selecciona los documentos posteriores
+// al cursor en un orden {campoFecha: -1, _id: -1}.
+func FiltroDespuesDe(campoFecha string, cur *Cursor) bson.M {
+	return bson.M{"$or": []bson.M{
+		{campoFecha: bson.M{"$lt": cur.Fecha}},
+		{campoFecha: cur.Fecha, "_id": bson.M{"$lt": cur.ID}},
+	}}
+}
+
+// FiltroDespuesDeAscendente es el equivalente de FiltroDespuesDe para un orden
+// {campoFecha: 1, _id: 1} (del más antiguo al más reciente).
+func FiltroDespuesDeAscendente(campoFecha string, cur *Cursor) bson.M {
+	return bson.M{"$or": []bson.M{
+		{campoFecha: bson.M{"$gt": cur.Fecha}},
+		{campoFecha: cur.Fecha, "_id": bson.M{"$gt": cur.ID}},
+	}}
+}
+
+// FiltroDespuesDeConteo es el equivalente de FiltroDespuesDe para un orden
+// {campoConteo: -1, _id: -1}.
+func FiltroDespuesDeConteo(campoConteo string, cur *Cursor) bson.M {
+	return bson.M{"$or": []bson.M{
+		{campoConteo: bson.M{"$lt": cur.Valor}},
+		{campoConteo: cur.Valor, "_id": bson.M{"$lt": cur.ID}},
+	}}
+}
+
+// ParsearLimite convierte el parámetro "limit" aplicando un valor por defecto y un máximo.
+func ParsearLimite(raw string, porDefecto, maximo int) int {
+	limite, err := strconv.Atoi(raw)
+	if err != nil || limite <= 0 {
+		return porDefecto
+	}
+	if limite > maximo {
+		return maximo
+	}
+	return limite
+}
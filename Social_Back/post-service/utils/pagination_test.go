@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCursorIdaYVuelta(t *testing.T) {
+	id := primitive.NewObjectID()
+	fecha := time.Date(2024, 5, 17, 10, 30, 0, 123000000, time.UTC)
+
+	cur, err := DecodificarCursor(CodificarCursor(fecha, id))
+	if err != nil || cur == nil {
+		t.Fatalf("DecodificarCursor(CodificarCursor) = %v, %v", cur, err)
+	}
+	if !cur.Fecha.Equal(fecha) || cur.ID != id || cur.Orden != "" || cur.Valor != 0 {
+		t.Errorf("cursor de fecha = %+v", cur)
+	}
+
+	cur, err = DecodificarCursor(CodificarCursorFecha("antiguos", fecha, id))
+	if err != nil || cur == nil {
+		t.Fatalf("DecodificarCursor(CodificarCursorFecha) = %v, %v", cur, err)
+	}
+	if !cur.Fecha.Equal(fecha) || cur.ID != id || cur.Orden != "antiguos" || cur.Valor != 0 {
+		t.Errorf("cursor de fecha con orden = %+v", cur)
+	}
+
+	cur, err = DecodificarCursor(CodificarCursorConteo("likes", 42, id))
+	if err != nil || cur == nil {
+		t.Fatalf("DecodificarCursor(CodificarCursorConteo) = %v, %v", cur, err)
+	}
+	if cur.Orden != "likes" || cur.Valor != 42 || cur.ID != id || !cur.Fecha.IsZero() {
+		t.Errorf("cursor de conteo = %+v", cur)
+	}
+}
+
+func TestDecodificarCursorVacio(t *testing.T) {
+	if cur, err := DecodificarCursor(""); cur != nil || err != nil {
+		t.Errorf("DecodificarCursor(\"\") = %v, %v; quiere nil, nil", cur, err)
+	}
+}
+
+func TestDecodificarCursorAlterado(t *testing.T) {
+	b64 := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	valido := CodificarCursor(time.Now(), primitive.NewObjectID())
+	casos := map[string]string{
+		"no es base64":          "%%%",
+		"base64 con relleno":    base64.URLEncoding.EncodeToString([]byte(`{"i":"65f0c0ffee0000000000abcd"}`)),
+		"no es JSON":            b64("hola"),
+		"JSON recortado":        valido[:len(valido)/2],
+		"sin ID":                b64(`{"f":"2024-05-17T10:30:00Z"}`),
+		"ID en cero":            b64(`{"i":"000000000000000000000000"}`),
+		"ID que no es ObjectID": b64(`{"i":"' || 1=1"}`),
+		"ID como objeto":        b64(`{"i":{"$gt":""}}`),
+		"valor no numérico":     b64(`{"v":"9e999","i":"65f0c0ffee0000000000abcd"}`),
+		"fecha inválida":        b64(`{"f":"ayer","i":"65f0c0ffee0000000000abcd"}`),
+		"arreglo":               b64(`[1,2,3]`),
+		"null":                  b64(`null`),
+	}
+	for nombre, s := range casos {
+		t.Run(nombre, func(t *testing.T) {
+			if cur, err := DecodificarCursor(s); err != ErrCursorInvalido {
+				t.Errorf("DecodificarCursor(%q) = %+v, %v; quiere ErrCursorInvalido", s, cur, err)
+			}
+		})
+	}
+}
+
+func TestParsearLimite(t *testing.T) {
+	casos := []struct {
+		raw    string
+		quiere int
+	}{
+		{"", 20},
+		{"abc", 20},
+		{"0", 20},
+		{"-5", 20},
+		{"10", 10},
+		{"100", 100},
+		{"1000", 100},
+		{"99999999999999999999", 20},
+	}
+	for _, c := range casos {
+		if got := ParsearLimite(c.raw, 20, 100); got != c.quiere {
+			t.Errorf("ParsearLimite(%q) = %d, quiere %d", c.raw, got, c.quiere)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"context"
+	"post-service/config"
+	"post-service/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ObtenerAjustes devuelve los ajustes del usuario. Si nunca los configuró se
+// devuelven los valores por defecto (cuenta pública).
+func ObtenerAjustes(usuarioID primitive.ObjectID) (models.AjustesUsuario, error) {
+	ajustes := models.AjustesUsuario{UsuarioID: usuarioID}
+	err := config.GetCollection("ajustes").FindOne(context.TODO(), bson.M{"_id": usuarioID}).Decode(&ajustes)
+	if err == mongo.ErrNoDocuments {
+		return ajustes, nil
+	}
+	return ajustes, err
+}
+
+// SigueA indica si seguidor tiene un follow aprobado hacia seguido.
+func SigueA(seguidor, seguido primitive.ObjectID) (bool, error) {
+	n, err := config.GetCollection("follows").CountDocuments(context.TODO(),
+		bson.M{"seguidorId": seguidor, "seguidoId": seguido})
+	return n > 0, err
+}
+
+// PuedeVerPostsDe indica si viewer puede ver los posts de autor: siempre si la
+// cuenta es pública o es la suya, y si es privada solo cuando lo sigue.
+// Un viewer cero representa a un visitante anónimo.
+func PuedeVerPostsDe(viewer, autor primitive.ObjectID) (bool, error) {
+	if viewer == autor {
+		return true, nil
+	}
+	ajustes, err := ObtenerAjustes(autor)
+	if err != nil {
+		return false, err
+	}
+	if !ajustes.CuentaPrivada {
+		return true, nil
+	}
+	if viewer.IsZero() {
+		return false, nil
+	}
+	return SigueA(viewer, autor)
+}
+
+// AutoresPrivadosOcultos lista las cuentas privadas cuyos posts viewer no puede
+// ver, para excluirlas con {"autorId": {"$nin": ...}} en los listados.
+func AutoresPrivadosOcultos(viewer primitive.ObjectID) ([]primitive.ObjectID, error) {
+	ctx := context.TODO()
+	valores, err := config.GetCollection("ajustes").Distinct(ctx, "_id", bson.M{"cuentaPrivada": true})
+	if err != nil || len(valores) == 0 {
+		return nil, err
+	}
+	privadas := IDsDeDistinct(valores)
+
+	permitidas := map[primitive.ObjectID]bool{viewer: true}
+	if !viewer.IsZero() {
+		seguidos, err := config.GetCollection("follows").Distinct(ctx, "seguidoId",
+			bson.M{"seguidorId": viewer, "seguidoId": bson.M{"$in": privadas}})
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range IDsDeDistinct(seguidos) {
+			permitidas[id] = true
+		}
+	}
+
+	ocultos := []primitive.ObjectID{}
+	for _, id := range privadas {
+		if !permitidas[id] {
+			ocultos = append(ocultos, id)
+		}
+	}
+	return ocultos, nil
+}
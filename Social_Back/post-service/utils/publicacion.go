@@ -0,0 +1,268 @@
+package utils
+
+import (
+	"context"
+	"log"
+	"os"
+	"post-service/config"
+	"post-service/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	intervaloPublicadorDefecto = 15 * time.Second
+	difusionAbandonada         = 10 * time.Minute // una difusión sin renovar desde hace más se retoma
+	renovarDifusion            = time.Minute      // cada cuánto se renueva difundiendo mientras se difunde
+)
+
+// Un post se publica al crearlo, al publicar un borrador o, si está programado,
+// cuando el publicador lo toma. En los tres casos queda con estado published y
+// difundiendo = ahora en el mismo update, y Difundir hace después lo que ven los
+// demás (timelines y notificaciones). difundiendo hace de concesión: se renueva
+// mientras dura la difusión y, si la instancia se cae a mitad de camino, el
+// publicador la retoma cuando vence.
+
+// EstaPublicado indica si el post ya se publicó.
+func EstaPublicado(post models.Post) bool {
+	return post.Estado == "" || post.Estado == models.EstadoPublicado
+}
+
+// FiltroPublicados selecciona los posts ya publicados (los anteriores a los
+// borradores no tienen estado).
+func FiltroPublicados() bson.M {
+	return bson.M{"estado": bson.M{"$in": bson.A{nil, models.EstadoPublicado}}}
+}
+
+// IniciarPublicador arranca el proceso que publica los posts programados cuyo
+// momento llegó (cada PUBLICADOR_INTERVALO, por defecto 15 segundos). Cada post
+// se toma con un update atómico, así que varias instancias pueden correrlo a la
+// vez sin publicar dos veces el mismo.
+func IniciarPublicador() {
+	intervalo, err := time.ParseDuration(os.Getenv("PUBLICADOR_INTERVALO"))
+	if err != nil || intervalo <= 0 {
+		intervalo = intervaloPublicadorDefecto
+	}
+	go func() {
+		for {
+			for publicarSiguiente() {
+			}
+			time.Sleep(intervalo)
+		}
+	}()
+}
+
+// publicarSiguiente toma un post programado que ya venció (o uno cuya difusión
+// quedó a medias), lo marca como publicado y lo difunde. Devuelve false si no
+// había ninguno.
+func publicarSiguiente() bool {
+	ahora := time.Now().Truncate(time.Millisecond)
+	filtro := bson.M{"$or": []bson.M{
+		{"estado": models.EstadoProgramado, "publicarEn": bson.M{"$lte": ahora}},
+		{"difundiendo": bson.M{"$lt": ahora.Add(-difusionAbandonada)}},
+	}}
+	update := bson.M{
+		"$set":   bson.M{"estado": models.EstadoPublicado, "difundiendo": ahora},
+		"$unset": bson.M{"publicarEn": ""},
+	}
+	var post models.Post
+	err := config.GetCollection("posts").FindOneAndUpdate(context.TODO(), filtro, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&post)
+	if err == mongo.ErrNoDocuments {
+		return false
+	}
+	if err != nil {
+		log.Println("Error tomando un post programado:", err)
+		return false
+	}
+	Difundir(post)
+	return true
+}
+
+// Difundir hace lo que sigue a la publicación de un post: registra sus tags,
+// notifica a los mencionados, al autor del post citado y a los seguidores que lo
+// pueden ver, actualiza las citas del original y lo copia en los timelines de los
+// seguidores. Como corre al publicar, un borrador o un post programado solo avisa
+// cuando de verdad se publica. Mientras corre renueva difundiendo, para que una
+// difusión larga no se retome en paralelo, y al terminar la borra si sigue siendo
+// suya. Si se retoma tras una caída, ni las copias en timelines ni los avisos a
+// seguidores se duplican.
+func Difundir(post models.Post) {
+	detener := mantenerDifusion(post)
+	RegistrarUsoTags(post.Tags, post.ID, post.AutorID, "post")
+	NotificarMenciones(post.Menciones, post.AutorID, post.ID, "Te mencionó en un post", nil)
+	if post.CitaDe != nil {
+		RecalcularReposts(*post.CitaDe)
+		var citado models.Post
+		err := config.GetCollection("posts").FindOne(context.TODO(), bson.M{"_id": *post.CitaDe},
+			options.FindOne().SetProjection(bson.M{"autorId": 1})).Decode(&citado)
+		if err == nil && citado.AutorID != post.AutorID {
+			CrearNotificacion("repost", post.AutorID, citado.AutorID, "Citó tu post", &post.ID)
+		} else if err != nil && err != mongo.ErrNoDocuments {
+			log.Println("Error buscando el post citado por", post.ID.Hex(), ":", err)
+		}
+	}
+	NotificarSeguidores(post)
+	DistribuirPost(post)
+	difundiendo := detener()
+
+	res, err := config.GetCollection("posts").UpdateOne(context.TODO(),
+		bson.M{"_id": post.ID, "difundiendo": difundiendo},
+		bson.M{"$unset": bson.M{"difundiendo": ""}},
+	)
+	if err != nil {
+		log.Println("Error terminando la difusión del post", post.ID.Hex(), ":", err)
+	} else if res.MatchedCount == 0 {
+		log.Println("La difusión del post", post.ID.Hex(), "la retomó otra instancia")
+	}
+}
+
+// mantenerDifusion renueva difundiendo cada renovarDifusion mientras el post se
+// difunde. Solo renueva si la difusión sigue siendo de esta instancia. La función
+// devuelta detiene la renovación y devuelve el último valor guardado.
+func mantenerDifusion(post models.Post) func() time.Time {
+	fin := make(chan struct{})
+	ultimo := make(chan time.Time)
+	go func() {
+		var difundiendo time.Time
+		if post.Difundiendo != nil {
+			difundiendo = post.Difundiendo.Truncate(time.Millisecond) // lo que guarda Mongo
+		}
+		ticker := time.NewTicker(renovarDifusion)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-fin:
+				ultimo <- difundiendo
+				return
+			case <-ticker.C:
+				nuevo := time.Now().Truncate(time.Millisecond)
+				res, err := config.GetCollection("posts").UpdateOne(context.TODO(),
+					bson.M{"_id": post.ID, "difundiendo": difundiendo},
+					bson.M{"$set": bson.M{"difundiendo": nuevo}},
+				)
+				if err != nil {
+					log.Println("Error renovando la difusión del post", post.ID.Hex(), ":", err)
+				} else if res.MatchedCount == 1 {
+					difundiendo = nuevo
+				}
+			}
+		}
+	}()
+	return func() time.Time {
+		close(fin)
+		return <-ultimo
+	}
+}
+
+// NotificarSeguidores avisa del post publicado a los seguidores del autor que lo
+// pueden ver. La audiencia se resuelve una vez para todos con la misma regla que
+// FiltroVisibles: todos los seguidores sin bloqueo con el autor si es público o
+// para seguidores (seguir al autor basta aunque la cuenta sea privada), los
+// miembros del grupo o los de la misma institución. Los mencionados ya recibieron
+// su notificación, y a quien ya se avisó en una difusión anterior no se repite.
+func NotificarSeguidores(post models.Post) {
+	ctx := context.TODO()
+	valores, err := config.GetCollection("follows").Distinct(ctx, "seguidorId", bson.M{"seguidoId": post.AutorID})
+	if err != nil {
+		log.Println("Error buscando seguidores para notificar el post", post.ID.Hex(), ":", err)
+		return
+	}
+	seguidores, err := audienciaDe(post, IDsDeDistinct(valores))
+	if err != nil {
+		log.Println("Error calculando quién puede ver el post", post.ID.Hex(), ":", err)
+		return
+	}
+	if len(seguidores) == 0 {
+		return
+	}
+
+	omitidos := map[primitive.ObjectID]bool{}
+	for _, m := range post.Menciones {
+		omitidos[m.UsuarioID] = true
+	}
+	bloqueados, err := UsuariosBloqueados(post.AutorID)
+	if err != nil {
+		log.Println("Error buscando bloqueos del autor del post", post.ID.Hex(), ":", err)
+		return
+	}
+	avisados, err := config.GetCollection("notificaciones").Distinct(ctx, "para",
+		bson.M{"tipo": "post", "postId": post.ID})
+	if err != nil {
+		log.Println("Error buscando avisos previos del post", post.ID.Hex(), ":", err)
+		return
+	}
+	for _, id := range append(bloqueados, IDsDeDistinct(avisados)...) {
+		omitidos[id] = true
+	}
+
+	for _, seguidorID := range seguidores {
+		if !omitidos[seguidorID] {
+			CrearNotificacion("post", post.AutorID, seguidorID, "Publicó un nuevo post", &post.ID)
+		}
+	}
+}
+
+// audienciaDe devuelve cuáles de los seguidores pueden ver el post según su
+// visibilidad, sin contar bloqueos.
+func audienciaDe(post models.Post, seguidores []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	ctx := context.TODO()
+	switch post.Visibilidad {
+	case "", models.VisibilidadPublica, models.VisibilidadSeguidores:
+		return seguidores, nil
+	case models.VisibilidadGrupo:
+		if post.GrupoID == nil {
+			return nil, nil
+		}
+		valores, err := config.GetCollection("grupos").Distinct(ctx, "miembros",
+			bson.M{"_id": *post.GrupoID, "miembros": bson.M{"$in": seguidores}})
+		if err != nil {
+			return nil, err
+		}
+		miembros := map[primitive.ObjectID]bool{}
+		for _, id := range IDsDeDistinct(valores) {
+			miembros[id] = true
+		}
+		return filtrarIDs(seguidores, miembros), nil
+	case models.VisibilidadInstitucion:
+		if post.Institucion == "" {
+			return nil, nil
+		}
+		cursor, err := config.GetCollection("users").Find(ctx, bson.M{"_id": bson.M{"$in": seguidores}},
+			options.Find().SetProjection(bson.M{"codigo_institucional": 1}))
+		if err != nil {
+			return nil, err
+		}
+		var usuarios []struct {
+			ID     primitive.ObjectID `bson:"_id"`
+			Codigo string             `bson:"codigo_institucional"`
+		}
+		if err := cursor.All(ctx, &usuarios); err != nil {
+			return nil, err
+		}
+		misma := map[primitive.ObjectID]bool{}
+		for _, u := range usuarios {
+			if Institucion(u.Codigo) == post.Institucion {
+				misma[u.ID] = true
+			}
+		}
+		return filtrarIDs(seguidores, misma), nil
+	}
+	return nil, nil // privada
+}
+
+// filtrarIDs devuelve los ids que están en incluidos, en el mismo orden.
+func filtrarIDs(ids []primitive.ObjectID, incluidos map[primitive.ObjectID]bool) []primitive.ObjectID {
+	var filtrados []primitive.ObjectID
+	for _, id := range ids {
+		if incluidos[id] {
+			filtrados = append(filtrados, id)
+		}
+	}
+	return filtrados
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"math"
+	"os"
+	"post-service/models"
+	"strconv"
+)
+
+// PesosRanking define cuánto aporta cada señal al puntaje del feed "para ti".
+type PesosRanking struct {
+	Recencia          float64 // peso del decaimiento por antigüedad
+	Velocidad         float64 // peso de likes/comentarios por hora
+	Afinidad          float64 // peso de las interacciones con el autor
+	Intereses         float64 // peso de la coincidencia de tags/categoría
+	VidaMediaHoras    float64 // horas en que la recencia cae a la mitad
+	PenalizacionAutor float64 // factor (0-1] por cada post previo del mismo autor
+}
+
+// CargarPesosRanking devuelve los pesos por defecto sobrescritos por las variables
+// RANKING_PESO_RECENCIA, RANKING_PESO_VELOCIDAD, RANKING_PESO_AFINIDAD,
+// RANKING_PESO_INTERESES, RANKING_VIDA_MEDIA_HORAS y RANKING_PENALIZACION_AUTOR.
+func CargarPesosRanking() PesosRanking {
+	p := PesosRanking{
+		Recencia:          envFloat("RANKING_PESO_RECENCIA", 1.0),
+		Velocidad:         envFloat("RANKING_PESO_VELOCIDAD", 1.0),
+		Afinidad:          envFloat("RANKING_PESO_AFINIDAD", 1.5),
+		Intereses:         envFloat("RANKING_PESO_INTERESES", 1.0),
+		VidaMediaHoras:    envFloat("RANKING_VIDA_MEDIA_HORAS", 24),
+		PenalizacionAutor: envFloat("RANKING_PENALIZACION_AUTOR", 0.6),
+	}
+	if p.VidaMediaHoras <= 0 {
+		p.VidaMediaHoras = 24
+	}
+	if p.PenalizacionAutor <= 0 || p.PenalizacionAutor > 1 {
+		p.PenalizacionAutor = 0.6
+	}
+	return p
+}
+
+// SenalesPost son los datos de un post candidato (relativos a quien consulta) que
+// se usan para puntuarlo.
+type SenalesPost struct {
+	EdadHoras     float64
+	Likes         int64
+	Comentarios   int64
+	Interacciones int     // likes y comentarios de quien consulta a posts del autor
+	SigueAutor    bool    // quien consulta sigue al autor
+	Coincidencia  float64 // 0-1: qué tanto coinciden tags/categoría con su historial
+}
+
+// PuntuarPost calcula el desglose del puntaje base de un post (sin diversidad).
+func PuntuarPost(p PesosRanking, s SenalesPost) models.DesglosePuntaje {
+	d := models.DesglosePuntaje{Diversidad: 1}
+
+	d.Recencia = math.Exp(-math.Ln2 * s.EdadHoras / p.VidaMediaHoras)
+
+	// Los comentarios cuentan doble: requieren más esfuerzo que un like
+	d.Velocidad = math.Log1p(float64(s.Likes+2*s.Comentarios) / (s.EdadHoras + 2))
+
+	d.Afinidad = math.Log1p(float64(s.Interacciones))
+	if s.SigueAutor {
+		d.Afinidad += 1
+	}
+
+	d.Intereses = s.Coincidencia
+
+	d.Base = p.Recencia*d.Recencia + p.Velocidad*d.Velocidad + p.Afinidad*d.Afinidad + p.Intereses*d.Intereses
+	return d
+}
+
+// Diversificar reordena los posts de mayor a menor puntaje penalizando a los autores
+// que ya aparecieron: el k-ésimo post de un mismo autor se multiplica por
+// penalizacion^k, para que una sola cuenta no acapare el feed.
+func Diversificar(posts []models.PostRankeado, desgloses []models.DesglosePuntaje, penalizacion float64) {
+	vistos := map[string]int{}
+	for i := range posts {
+		mejor, mejorPuntaje := i, -1.0
+		for j := i; j < len(posts); j++ {
+			puntaje := desgloses[j].Base * math.Pow(penalizacion, float64(vistos[posts[j].AutorID.Hex()]))
+			if puntaje > mejorPuntaje {
+				mejor, mejorPuntaje = j, puntaje
+			}
+		}
+		posts[i], posts[mejor] = posts[mejor], posts[i]
+		desgloses[i], desgloses[mejor] = desgloses[mejor], desgloses[i]
+
+		autor := posts[i].AutorID.Hex()
+		desgloses[i].Diversidad = math.Pow(penalizacion, float64(vistos[autor]))
+		posts[i].Puntaje = mejorPuntaje
+		vistos[autor]++
+	}
+}
+
+// envFloat lee una variable de entorno numérica con valor por defecto.
+func envFloat(nombre string, porDefecto float64) float64 {
+	if v, err := strconv.ParseFloat(os.Getenv(nombre), 64); err == nil {
+		return v
+	}
+	return porDefecto
+}
@@ -0,0 +1,258 @@
+package utils
+
+import (
+	"context"
+	"log"
+	"os"
+	"post-service/config"
+	"post-service/models"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// tiposReaccionPorDefecto se usa si REACCIONES no está configurada.
+const tiposReaccionPorDefecto = "like,love,insightful,funny"
+
+// TiposReaccion devuelve las reacciones permitidas, configurables con la variable
+// REACCIONES (lista separada por comas). "like" siempre está incluida.
+func TiposReaccion() []string {
+	lista := os.Getenv("REACCIONES")
+	if strings.TrimSpace(lista) == "" {
+		lista = tiposReaccionPorDefecto
+	}
+	tipos := []string{"like"}
+	vistos := map[string]bool{"like": true}
+	for _, t := range strings.Split(lista, ",") {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t != "" && !vistos[t] {
+			vistos[t] = true
+			tipos = append(tipos, t)
+		}
+	}
+	return tipos
+}
+
+// EsTipoReaccion indica si tipo es una de las reacciones permitidas.
+func EsTipoReaccion(tipo string) bool {
+	for _, t := range TiposReaccion() {
+		if t == tipo {
+			return true
+		}
+	}
+	return false
+}
+
+// FiltroReaccion selecciona las reacciones a un post (comentarioID nil) o a uno de
+// sus comentarios, opcionalmente de un único usuario.
+func FiltroReaccion(postID primitive.ObjectID, comentarioID *primitive.ObjectID) bson.M {
+	filtro := bson.M{"postId": postID, "comentarioId": nil}
+	if comentarioID != nil {
+		filtro["comentarioId"] = *comentarioID
+	}
+	return filtro
+}
+
+// FijarReaccion deja la reacción de usuario en tipo, creándola o cambiando la que
+// tuviera. Es idempotente: repetirla no cambia nada. Devuelve el tipo anterior
+// ("" si no había reacción).
+func FijarReaccion(postID primitive.ObjectID, comentarioID *primitive.ObjectID, usuarioID primitive.ObjectID, tipo string) (string, error) {
+	filtro := FiltroReaccion(postID, comentarioID)
+	filtro["usuarioId"] = usuarioID
+	update := bson.M{
+		"$set":         bson.M{"tipo": tipo},
+		"$setOnInsert": bson.M{"fecha": time.Now()},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)
+
+	var previa models.Reaccion
+	err := config.GetCollection("reacciones").FindOneAndUpdate(context.TODO(), filtro, update, opts).Decode(&previa)
+	if mongo.IsDuplicateKeyError(err) {
+		// Otra petición insertó la reacción a la vez: ahora ya existe y se actualiza
+		err = config.GetCollection("reacciones").FindOneAndUpdate(context.TODO(), filtro, update, opts).Decode(&previa)
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		return "", err
+	}
+	if previa.Tipo != tipo {
+		ajustarContadoresReaccion(postID, comentarioID, previa.Tipo, tipo)
+	}
+	return previa.Tipo, nil
+}
+
+// QuitarReaccion borra la reacción de usuario, si existe. Devuelve el tipo que tenía.
+func QuitarReaccion(postID primitive.ObjectID, comentarioID *primitive.ObjectID, usuarioID primitive.ObjectID) (string, error) {
+	filtro := FiltroReaccion(postID, comentarioID)
+	filtro["usuarioId"] = usuarioID
+
+	var previa models.Reaccion
+	err := config.GetCollection("reacciones").FindOneAndDelete(context.TODO(), filtro).Decode(&previa)
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	ajustarContadoresReaccion(postID, comentarioID, previa.Tipo, "")
+	return previa.Tipo, nil
+}
+
+// ajustarContadoresReaccion actualiza reacciones.<tipo> y totalLikes del post o
+// comentario al pasar de la reacción anterior a la nueva ("" = ninguna).
+func ajustarContadoresReaccion(postID primitive.ObjectID, comentarioID *primitive.ObjectID, anterior, nuevo string) {
+	inc := bson.M{}
+	if anterior != "" {
+		inc["reacciones."+anterior] = -1
+	}
+	if nuevo != "" {
+		inc["reacciones."+nuevo] = 1
+	}
+	switch {
+	case anterior == "":
+		inc["totalLikes"] = 1
+	case nuevo == "":
+		inc["totalLikes"] = -1
+	}
+
+	coleccion, id := "posts", postID
+	if comentarioID != nil {
+		coleccion, id = "comentarios", *comentarioID
+	}
+	_, err := config.GetCollection(coleccion).UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$inc": inc})
+	if err != nil {
+		log.Println("Error actualizando contadores de reacciones de", id.Hex(), ":", err)
+	}
+}
+
+// ContarReacciones cuenta por tipo las reacciones a un post o comentario.
+func ContarReacciones(postID primitive.ObjectID, comentarioID *primitive.ObjectID) (map[string]int64, int64, error) {
+	ctx := context.TODO()
+	cur, err := config.GetCollection("reacciones").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: FiltroReaccion(postID, comentarioID)}},
+		{{Key: "$group", Value: bson.M{"_id": "$tipo", "n": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	var filas []struct {
+		Tipo string `bson:"_id"`
+		N    int64  `bson:"n"`
+	}
+	if err := cur.All(ctx, &filas); err != nil {
+		return nil, 0, err
+	}
+	conteos := map[string]int64{}
+	var total int64
+	for _, f := range filas {
+		conteos[f.Tipo] = f.N
+		total += f.N
+	}
+	return conteos, total, nil
+}
+
+// MigrarLikes pasa a "reacciones" (tipo like) los likes de las colecciones
+// anteriores "likes" y "comentario_likes", y completa reacciones.like en los posts
+// y comentarios que solo tenían totalLikes (a los que no lo tienen les pone 0).
+// Cada like se borra solo después de migrarlo (o si era de un comentario que ya
+// no existe), así que volver a ejecutarla retoma lo que faltó.
+func MigrarLikes() {
+	ctx := context.TODO()
+	reacciones := config.GetCollection("reacciones")
+	migrados := 0
+
+	// reaccionDe devuelve ok = false si el like no tiene a qué migrarse y se puede
+	// borrar, y un error si no se pudo saber: en ese caso el like se conserva
+	migrar := func(coleccion string, reaccionDe func(doc bson.M) (models.Reaccion, bool, error)) {
+		origen := config.GetCollection(coleccion)
+		cur, err := origen.Find(ctx, bson.M{})
+		if err != nil {
+			log.Println("Error leyendo", coleccion, "para migrar:", err)
+			return
+		}
+		defer cur.Close(ctx)
+		for cur.Next(ctx) {
+			var doc bson.M
+			if err := cur.Decode(&doc); err != nil {
+				continue
+			}
+			r, ok, err := reaccionDe(doc)
+			if err != nil {
+				log.Println("Error migrando el like", doc["_id"], "de", coleccion, ":", err)
+				continue
+			}
+			if ok {
+				filtro := FiltroReaccion(r.PostID, r.ComentarioID)
+				filtro["usuarioId"] = r.UsuarioID
+				_, err := reacciones.UpdateOne(ctx, filtro,
+					bson.M{"$setOnInsert": bson.M{"tipo": "like", "fecha": r.Fecha}},
+					options.Update().SetUpsert(true),
+				)
+				if err != nil && !mongo.IsDuplicateKeyError(err) {
+					log.Println("Error migrando like a reacciones:", err)
+					continue
+				}
+				migrados++
+			}
+			if _, err := origen.DeleteOne(ctx, bson.M{"_id": doc["_id"]}); err != nil {
+				log.Println("Error borrando el like migrado", doc["_id"], "de", coleccion, ":", err)
+			}
+		}
+	}
+
+	migrar("likes", func(doc bson.M) (models.Reaccion, bool, error) {
+		postID, ok1 := doc["postId"].(primitive.ObjectID)
+		usuarioID, ok2 := doc["usuarioId"].(primitive.ObjectID)
+		fecha, _ := doc["fecha"].(primitive.DateTime)
+		return models.Reaccion{PostID: postID, UsuarioID: usuarioID, Fecha: fecha.Time()}, ok1 && ok2, nil
+	})
+	migrar("comentario_likes", func(doc bson.M) (models.Reaccion, bool, error) {
+		comentarioID, ok1 := doc["comentarioId"].(primitive.ObjectID)
+		usuarioID, ok2 := doc["usuarioId"].(primitive.ObjectID)
+		fecha, _ := doc["fecha"].(primitive.DateTime)
+		if !ok1 || !ok2 {
+			return models.Reaccion{}, false, nil
+		}
+		var comentario models.Comentario
+		err := config.GetCollection("comentarios").FindOne(ctx, bson.M{"_id": comentarioID},
+			options.FindOne().SetProjection(bson.M{"postId": 1})).Decode(&comentario)
+		if err == mongo.ErrNoDocuments {
+			return models.Reaccion{}, false, nil
+		}
+		if err != nil {
+			return models.Reaccion{}, false, err
+		}
+		return models.Reaccion{
+			PostID:       comentario.PostID,
+			ComentarioID: &comentarioID,
+			UsuarioID:    usuarioID,
+			Fecha:        fecha.Time(),
+		}, true, nil
+	})
+
+	// Hasta ahora totalLikes solo contaba likes, y los documentos más viejos ni lo
+	// tienen: sin él no aparecerían al paginar por likes (el cursor compara con $lt)
+	completar := mongo.Pipeline{{{Key: "$set", Value: bson.M{"reacciones": bson.M{"like": "$totalLikes"}}}}}
+	for _, coleccion := range []string{"posts", "comentarios"} {
+		_, err := config.GetCollection(coleccion).UpdateMany(ctx,
+			bson.M{"totalLikes": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"totalLikes": 0}},
+		)
+		if err != nil {
+			log.Println("Error completando totalLikes en", coleccion, ":", err)
+		}
+		_, err = config.GetCollection(coleccion).UpdateMany(ctx,
+			bson.M{"totalLikes": bson.M{"$gt": 0}, "reacciones": bson.M{"$exists": false}},
+			completar,
+		)
+		if err != nil {
+			log.Println("Error completando reacciones en", coleccion, ":", err)
+		}
+	}
+	if migrados > 0 {
+		log.Println("Likes migrados a reacciones:", migrados)
+	}
+}
@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"context"
+	"log"
+	"post-service/config"
+	"post-service/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Un repost se guarda como un post del usuario que comparte, sin contenido propio
+// y con repostDe apuntando al original. Así aparece en su perfil y en el feed de
+// sus seguidores (al vuelo o por DistribuirPost) sin tratamiento especial, y al
+// leerlo se completa con el original (ver Original en models.Post).
+
+// Repostear comparte el post original en nombre de usuario. Si ya lo había
+// compartido devuelve ese repost con creado = false.
+func Repostear(usuarioID primitive.ObjectID, original models.Post) (models.Post, bool, error) {
+	repost := models.Post{
+		ID:          primitive.NewObjectID(),
+		Tags:        []string{},
+		AutorID:     usuarioID,
+		FechaCreado: time.Now(),
+		RepostDe:    &original.ID,
+	}
+	posts := config.GetCollection("posts")
+	_, err := posts.InsertOne(context.TODO(), repost)
+	if mongo.IsDuplicateKeyError(err) {
+		var existente models.Post
+		err := posts.FindOne(context.TODO(), bson.M{"autorId": usuarioID, "repostDe": original.ID}).Decode(&existente)
+		return existente, false, err
+	}
+	if err != nil {
+		return models.Post{}, false, err
+	}
+
+	RecalcularReposts(original.ID)
+	go DistribuirPost(repost)
+	return repost, true, nil
+}
+
+// DeshacerRepost borra el repost que usuario hizo del post original. Devuelve
+// false si no lo había compartido.
+func DeshacerRepost(usuarioID, originalID primitive.ObjectID) (bool, error) {
+	var repost models.Post
+	err := config.GetCollection("posts").FindOne(context.TODO(), bson.M{"autorId": usuarioID, "repostDe": originalID}).Decode(&repost)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	// La purga recalcula totalReposts del original
+	if err := PurgarPost(repost); err != nil {
+		return false, err
+	}
+	_, err = config.GetCollection("notificaciones").DeleteMany(context.TODO(), bson.M{
+		"tipo": "repost", "de": usuarioID, "postId": originalID,
+	})
+	if err != nil {
+		log.Println("Error borrando la notificación del repost:", err)
+	}
+	return true, nil
+}
+
+// RecalcularReposts cuenta de nuevo los reposts y citas publicados del post. Se
+// recalcula en lugar de sumar para que repetirlo (p. ej. al retomar una purga)
+// no desajuste los contadores.
+func RecalcularReposts(postID primitive.ObjectID) {
+	ctx := context.TODO()
+	posts := config.GetCollection("posts")
+	reposts, err := posts.CountDocuments(ctx, bson.M{"repostDe": postID})
+	if err != nil {
+		log.Println("Error contando reposts del post", postID.Hex(), ":", err)
+		return
+	}
+	publicadas := FiltroPublicados()
+	publicadas["citaDe"] = postID
+	citas, err := posts.CountDocuments(ctx, publicadas)
+	if err != nil {
+		log.Println("Error contando citas del post", postID.Hex(), ":", err)
+		return
+	}
+	_, err = posts.UpdateOne(ctx, bson.M{"_id": postID}, bson.M{"$set": bson.M{"totalReposts": reposts, "totalCitas": citas}})
+	if err != nil {
+		log.Println("Error actualizando reposts del post", postID.Hex(), ":", err)
+	}
+}
+
+// OriginalesVisibles carga los posts compartidos o citados por posts que viewer
+// puede ver (según FiltroListado).
+func OriginalesVisibles(viewer primitive.ObjectID, posts []*models.Post) (map[primitive.ObjectID]*models.Post, error) {
+	originales := map[primitive.ObjectID]*models.Post{}
+	ids := []primitive.ObjectID{}
+	for _, p := range posts {
+		if id := IDOriginal(*p); id != nil {
+			ids = append(ids, *id)
+		}
+	}
+	if len(ids) == 0 {
+		return originales, nil
+	}
+
+	visibles, err := FiltroListado(viewer)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := config.GetCollection("posts").Find(context.TODO(), bson.M{"$and": []bson.M{{"_id": bson.M{"$in": ids}}, visibles}},
+		options.Find().SetProjection(SinTextoAdjuntos))
+	if err != nil {
+		return nil, err
+	}
+	var encontrados []models.Post
+	if err := cur.All(context.TODO(), &encontrados); err != nil {
+		return nil, err
+	}
+	for i := range encontrados {
+		originales[encontrados[i].ID] = &encontrados[i]
+	}
+	return originales, nil
+}
+
+// IDOriginal devuelve el post que comparte o cita p, o nil si no es ninguna de
+// las dos cosas.
+func IDOriginal(p models.Post) *primitive.ObjectID {
+	if p.RepostDe != nil {
+		return p.RepostDe
+	}
+	return p.CitaDe
+}
@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Idiomas aceptados por el índice de texto de MongoDB (campo "idioma").
+const (
+	IdiomaEspanol = "spanish"
+	IdiomaIngles  = "english"
+)
+
+// Normalizar pasa el texto a minúsculas y quita tildes y diéresis ("Programación" → "programacion").
+func Normalizar(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	sinTildes, _, err := transform.String(t, s)
+	if err != nil {
+		sinTildes = s
+	}
+	return strings.ToLower(sinTildes)
+}
+
+// Palabras muy frecuentes de cada idioma, usadas solo para adivinar en qué idioma
+// está escrito un texto.
+var (
+	frecuentesEspanol = map[string]bool{
+		"el": true, "la": true, "los": true, "las": true, "de": true, "del": true, "que": true,
+		"y": true, "en": true, "un": true, "una": true, "por": true, "para": true, "con": true,
+		"es": true, "como": true, "pero": true, "se": true, "al": true, "lo": true,
+	}
+	frecuentesIngles = map[string]bool{
+		"the": true, "and": true, "of": true, "to": true, "in": true, "is": true, "for": true,
+		"with": true, "on": true, "that": true, "this": true, "it": true, "how": true,
+		"are": true, "be": true, "from": true, "an": true, "or": true, "you": true,
+	}
+)
+
+// DetectarIdioma devuelve IdiomaIngles si el texto tiene más palabras frecuentes del
+// inglés que del español; en cualquier otro caso IdiomaEspanol.
+func DetectarIdioma(texto string) string {
+	es, en := 0, 0
+	for _, palabra := range Palabras(Normalizar(texto)) {
+		if frecuentesEspanol[palabra] {
+			es++
+		}
+		if frecuentesIngles[palabra] {
+			en++
+		}
+	}
+	if en > es {
+		return IdiomaIngles
+	}
+	return IdiomaEspanol
+}
+
+// Palabras separa el texto en palabras formadas por letras y números.
+func Palabras(texto string) []string {
+	return strings.FieldsFunc(texto, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// ResaltarFragmento devuelve un extracto de como máximo largo runas alrededor de la
+// primera coincidencia con terminos, envolviendo cada coincidencia en <mark></mark>.
+// El resultado es HTML: el texto se escapa y <mark> es la única etiqueta que
+// contiene, así que puede insertarse tal cual aunque el texto venga de un usuario.
+// La comparación ignora mayúsculas y tildes y acepta variaciones de la terminación
+// ("programación" coincide con "programar"). Si no hay coincidencias devuelve "".
+func ResaltarFragmento(texto string, terminos []string, largo int) string {
+	raices := make([]string, 0, len(terminos))
+	for _, t := range terminos {
+		if r := raiz(Normalizar(t)); r != "" {
+			raices = append(raices, r)
+		}
+	}
+
+	// Posiciones (en runas) de cada palabra que coincide
+	type tramo struct{ inicio, fin int }
+	var tramos []tramo
+	original := []rune(texto)
+	for i := 0; i < len(original); {
+		if !unicode.IsLetter(original[i]) && !unicode.IsNumber(original[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(original) && (unicode.IsLetter(original[j]) || unicode.IsNumber(original[j])) {
+			j++
+		}
+		palabra := Normalizar(string(original[i:j]))
+		for _, r := range raices {
+			if strings.HasPrefix(palabra, r) {
+				tramos = append(tramos, tramo{i, j})
+				break
+			}
+		}
+		i = j
+	}
+	if len(tramos) == 0 {
+		return ""
+	}
+
+	// Ventana centrada en la primera coincidencia
+	inicio := tramos[0].inicio - largo/3
+	if inicio < 0 {
+		inicio = 0
+	}
+	fin := inicio + largo
+	if fin > len(original) {
+		fin = len(original)
+	}
+
+	var b strings.Builder
+	if inicio > 0 {
+		b.WriteString("…")
+	}
+	pos := inicio
+	for _, t := range tramos {
+		if t.inicio < inicio || t.fin > fin {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(original[pos:t.inicio])))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(string(original[t.inicio:t.fin])))
+		b.WriteString("</mark>")
+		pos = t.fin
+	}
+	b.WriteString(html.EscapeString(string(original[pos:fin])))
+	if fin < len(original) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// raiz recorta la terminación de un término para tolerar plurales y conjugaciones
+// al resaltar, dejando al menos cuatro letras.
+func raiz(termino string) string {
+	r := []rune(termino)
+	corte := len(r) - 3
+	if corte < 4 {
+		corte = len(r)
+		if corte > 4 {
+			corte = 4
+		}
+	}
+	return string(r[:corte])
+}
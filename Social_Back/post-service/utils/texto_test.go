@@ -0,0 +1,40 @@
+package utils
+
+import "testing"
+
+func TestResaltarFragmento(t *testing.T) {
+	casos := []struct {
+		nombre   string
+		texto    string
+		terminos []string
+		largo    int
+		quiere   string
+	}{
+		{"sin coincidencias", "Hola mundo", []string{"adios"}, 100, ""},
+		{"coincidencia simple", "Curso de Go", []string{"curso"}, 100, "<mark>Curso</mark> de Go"},
+		{"ignora tildes y terminación", "Programación en Go", []string{"programar"}, 100, "<mark>Programación</mark> en Go"},
+		{
+			"escapa el html alrededor",
+			`<img src=x onerror=alert(1)> curso`, []string{"curso"}, 100,
+			`&lt;img src=x onerror=alert(1)&gt; <mark>curso</mark>`,
+		},
+		{
+			"escapa el html que queda después",
+			`curso <script>alert("x")</script>`, []string{"curso"}, 100,
+			`<mark>curso</mark> &lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;`,
+		},
+		{
+			"una etiqueta cortada por la ventana sigue escapada",
+			`<b>curso</b>`, []string{"curso"}, 6,
+			`…b&gt;curs…`,
+		},
+		{"marca entre comillas", `"curso"`, []string{"curso"}, 100, `&#34;<mark>curso</mark>&#34;`},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			if got := ResaltarFragmento(c.texto, c.terminos, c.largo); got != c.quiere {
+				t.Errorf("ResaltarFragmento(%q) = %q, quiere %q", c.texto, got, c.quiere)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package utils
+
+import (
+	"context"
+	"log"
+	"os"
+	"post-service/config"
+	"post-service/models"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// El feed se arma al vuelo (fan-out-on-read) leyendo los posts de los seguidos.
+// Para quienes siguen a muchas cuentas se mantiene además un timeline precalculado
+// (fan-out-on-write) en la colección "timelines" con los posts recientes.
+const (
+	ventanaTimeline     = 30 * 24 * time.Hour // antigüedad máxima de las entradas copiadas
+	maxEntradasTimeline = 2000                // entradas copiadas al materializar un timeline
+)
+
+// UmbralTimeline devuelve a partir de cuántos seguidos se materializa el timeline de
+// un usuario. Se configura con FEED_FANOUT_UMBRAL; 0 desactiva el fan-out-on-write.
+func UmbralTimeline() int64 {
+	if v := os.Getenv("FEED_FANOUT_UMBRAL"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return 1000
+}
+
+// TimelineMaterializado indica si el feed del usuario se sirve desde "timelines".
+func TimelineMaterializado(usuarioID primitive.ObjectID) (bool, error) {
+	if UmbralTimeline() == 0 {
+		return false, nil
+	}
+	ajustes, err := ObtenerAjustes(usuarioID)
+	return ajustes.TimelineMaterializado, err
+}
+
+// DistribuirPost copia un post nuevo en los timelines materializados de los
+// seguidores del autor (y en el del propio autor si lo tiene).
+func DistribuirPost(post models.Post) {
+	if UmbralTimeline() == 0 {
+		return
+	}
+	ctx := context.TODO()
+
+	// Los usuarios con timeline materializado son pocos: se parte de ellos
+	valores, err := config.GetCollection("ajustes").Distinct(ctx, "_id", bson.M{"timelineMaterializado": true})
+	if err != nil {
+		log.Println("Error buscando timelines materializados:", err)
+		return
+	}
+	materializados := IDsDeDistinct(valores)
+	if len(materializados) == 0 {
+		return
+	}
+
+	valores, err = config.GetCollection("follows").Distinct(ctx, "seguidorId", bson.M{
+		"seguidoId":  post.AutorID,
+		"seguidorId": bson.M{"$in": materializados},
+	})
+	if err != nil {
+		log.Println("Error buscando seguidores para distribuir el post:", err)
+		return
+	}
+	destinos := IDsDeDistinct(valores)
+	for _, id := range materializados {
+		if id == post.AutorID {
+			destinos = append(destinos, id)
+		}
+	}
+
+	entradas := make([]interface{}, 0, len(destinos))
+	for _, usuarioID := range destinos {
+		entradas = append(entradas, models.TimelineEntrada{
+			ID:          primitive.NewObjectID(),
+			UsuarioID:   usuarioID,
+			PostID:      post.ID,
+			AutorID:     post.AutorID,
+			FechaCreado: post.FechaCreado,
+		})
+	}
+	insertarEntradas(entradas)
+}
+
+// AgregarAutorATimeline se llama cuando usuario empieza a seguir a autor: copia los
+// posts recientes del autor si el timeline ya está materializado, o lo materializa
+// si con este follow se alcanzó el umbral.
+func AgregarAutorATimeline(usuarioID, autorID primitive.ObjectID) {
+	umbral := UmbralTimeline()
+	if umbral == 0 {
+		return
+	}
+
+	materializado, err := TimelineMaterializado(usuarioID)
+	if err != nil {
+		log.Println("Error leyendo ajustes de timeline:", err)
+		return
+	}
+	if materializado {
+		// Solo se copian posts dentro del rango que ya cubre el timeline: lo anterior
+		// a la entrada más antigua se sigue leyendo al vuelo y no debe duplicarse
+		desde := time.Now().Add(-ventanaTimeline)
+		var masAntigua models.TimelineEntrada
+		err := config.GetCollection("timelines").FindOne(context.TODO(),
+			bson.M{"usuarioId": usuarioID},
+			options.FindOne().SetSort(bson.D{{Key: "fechaCreado", Value: 1}}),
+		).Decode(&masAntigua)
+		if err == nil && masAntigua.FechaCreado.After(desde) {
+			desde = masAntigua.FechaCreado
+		}
+		copiarPostsRecientes(usuarioID, []primitive.ObjectID{autorID}, desde)
+		return
+	}
+
+	seguidos, err := config.GetCollection("follows").CountDocuments(context.TODO(), bson.M{"seguidorId": usuarioID})
+	if err != nil {
+		log.Println("Error contando seguidos:", err)
+		return
+	}
+	if seguidos >= umbral {
+		MaterializarTimeline(usuarioID)
+	}
+}
+
+// QuitarAutorDeTimeline elimina del timeline materializado de usuario los posts de
+// autor (al dejar de seguirlo o al bloquearse). Si con eso usuario quedó muy por
+// debajo del umbral, su feed vuelve a armarse al vuelo.
+func QuitarAutorDeTimeline(usuarioID, autorID primitive.ObjectID) {
+	_, err := config.GetCollection("timelines").DeleteMany(context.TODO(), bson.M{"usuarioId": usuarioID, "autorId": autorID})
+	if err != nil {
+		log.Println("Error quitando autor del timeline:", err)
+	}
+	desmaterializarSiCorresponde(usuarioID)
+}
+
+// desmaterializarSiCorresponde deja de precalcular el timeline de usuario cuando
+// sigue a menos de la mitad del umbral. El margen evita materializar y borrar el
+// timeline una y otra vez si alguien sigue y deja de seguir cerca del umbral.
+func desmaterializarSiCorresponde(usuarioID primitive.ObjectID) {
+	materializado, err := TimelineMaterializado(usuarioID)
+	if err != nil || !materializado {
+		return
+	}
+	ctx := context.TODO()
+	seguidos, err := config.GetCollection("follows").CountDocuments(ctx, bson.M{"seguidorId": usuarioID})
+	if err != nil {
+		log.Println("Error contando seguidos:", err)
+		return
+	}
+	if seguidos >= UmbralTimeline()/2 {
+		return
+	}
+
+	// Primero la bandera, para que el feed deje de leer el timeline antes de vaciarlo
+	_, err = config.GetCollection("ajustes").UpdateOne(ctx, bson.M{"_id": usuarioID},
+		bson.M{"$set": bson.M{"timelineMaterializado": false}})
+	if err != nil {
+		log.Println("Error desmarcando timeline materializado:", err)
+		return
+	}
+	if _, err := config.GetCollection("timelines").DeleteMany(ctx, bson.M{"usuarioId": usuarioID}); err != nil {
+		log.Println("Error vaciando el timeline:", err)
+	}
+	log.Println("Timeline de", usuarioID.Hex(), "vuelve a armarse al vuelo con", seguidos, "seguidos")
+}
+
+// QuitarPostDeTimelines elimina un post de todos los timelines materializados.
+func QuitarPostDeTimelines(postID primitive.ObjectID) {
+	_, err := config.GetCollection("timelines").DeleteMany(context.TODO(), bson.M{"postId": postID})
+	if err != nil {
+		log.Println("Error quitando post de los timelines:", err)
+	}
+}
+
+// MaterializarTimeline marca el timeline del usuario como precalculado y copia en
+// él los posts recientes de sus seguidos y los suyos.
+func MaterializarTimeline(usuarioID primitive.ObjectID) {
+	ctx := context.TODO()
+	_, err := config.GetCollection("ajustes").UpdateOne(ctx,
+		bson.M{"_id": usuarioID},
+		bson.M{"$set": bson.M{"timelineMaterializado": true}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		log.Println("Error marcando timeline materializado:", err)
+		return
+	}
+
+	valores, err := config.GetCollection("follows").Distinct(ctx, "seguidoId", bson.M{"seguidorId": usuarioID})
+	if err != nil {
+		log.Println("Error leyendo seguidos para materializar timeline:", err)
+		return
+	}
+	autores := append(IDsDeDistinct(valores), usuarioID)
+	copiarPostsRecientes(usuarioID, autores, time.Now().Add(-ventanaTimeline))
+	log.Println("Timeline materializado para", usuarioID.Hex(), "con", len(autores), "autores")
+}
+
+// copiarPostsRecientes inserta en el timeline de usuario los posts de autores
+// publicados desde la fecha indicada (como máximo maxEntradasTimeline).
+func copiarPostsRecientes(usuarioID primitive.ObjectID, autores []primitive.ObjectID, desde time.Time) {
+	ctx := context.TODO()
+	opts := options.Find().
+		SetSort(bson.D{{Key: "fechaCreado", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(maxEntradasTimeline).
+		SetProjection(bson.M{"_id": 1, "autorId": 1, "fechaCreado": 1})
+	filtro := FiltroPublicados()
+	filtro["autorId"] = bson.M{"$in": autores}
+	filtro["fechaCreado"] = bson.M{"$gte": desde}
+	cur, err := config.GetCollection("posts").Find(ctx, filtro, opts)
+	if err != nil {
+		log.Println("Error leyendo posts para el timeline:", err)
+		return
+	}
+	var posts []models.Post
+	if err := cur.All(ctx, &posts); err != nil {
+		log.Println("Error leyendo posts para el timeline:", err)
+		return
+	}
+
+	entradas := make([]interface{}, 0, len(posts))
+	for _, p := range posts {
+		entradas = append(entradas, models.TimelineEntrada{
+			ID:          primitive.NewObjectID(),
+			UsuarioID:   usuarioID,
+			PostID:      p.ID,
+			AutorID:     p.AutorID,
+			FechaCreado: p.FechaCreado,
+		})
+	}
+	insertarEntradas(entradas)
+}
+
+// insertarEntradas guarda entradas de timeline ignorando las que ya existían.
+func insertarEntradas(entradas []interface{}) {
+	if len(entradas) == 0 {
+		return
+	}
+	_, err := config.GetCollection("timelines").InsertMany(context.TODO(), entradas, options.InsertMany().SetOrdered(false))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		log.Println("Error guardando entradas de timeline:", err)
+	}
+}
@@ -0,0 +1,282 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"post-service/config"
+	"post-service/models"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	maxIntentosTrabajo  = 3                // intentos antes de marcar un trabajo como fallido
+	trabajoAbandonado   = 30 * time.Minute // un trabajo "procesando" sin renovar desde hace más se reintenta
+	renovarTrabajo      = 5 * time.Minute  // cada cuánto el worker renueva "inicio" mientras procesa
+	esperaSinTrabajos   = 2 * time.Second  // pausa de un worker cuando la cola está vacía
+	esperaReintento     = time.Minute      // se multiplica por el número de intentos
+	workersMediaDefecto = 2
+)
+
+// procesadores indica qué función procesa cada tipo de adjunto.
+var procesadores = map[string]func(*models.Adjunto) error{
+	"imagen":    ProcesarImagen,
+	"video":     ProcesarVideo,
+	"documento": ProcesarDocumento,
+}
+
+// RequiereProcesamiento indica si los adjuntos de este tipo pasan por la cola de
+// procesamiento en segundo plano.
+func RequiereProcesamiento(tipo string) bool {
+	_, ok := procesadores[tipo]
+	return ok
+}
+
+// EncolarProcesamiento agrega el adjunto a la cola "trabajos_media" si su tipo
+// requiere procesamiento. El adjunto debe estar ya guardado.
+func EncolarProcesamiento(adjunto *models.Adjunto) {
+	if !RequiereProcesamiento(adjunto.Tipo) {
+		return
+	}
+	trabajo := models.TrabajoMedia{
+		ID:        primitive.NewObjectID(),
+		Tipo:      adjunto.Tipo,
+		AdjuntoID: adjunto.ID,
+		Estado:    "pendiente",
+		Fecha:     time.Now(),
+	}
+	if _, err := config.GetCollection("trabajos_media").InsertOne(context.TODO(), trabajo); err != nil {
+		log.Println("Error encolando el procesamiento de", adjunto.ID.Hex(), ":", err)
+	}
+}
+
+// IniciarWorkers arranca los workers que procesan la cola de adjuntos. La cantidad
+// se configura con MEDIA_WORKERS (por defecto 2).
+func IniciarWorkers() {
+	n, err := strconv.Atoi(os.Getenv("MEDIA_WORKERS"))
+	if err != nil || n <= 0 {
+		n = workersMediaDefecto
+	}
+	for range n {
+		go func() {
+			for {
+				if !procesarSiguiente() {
+					time.Sleep(esperaSinTrabajos)
+				}
+			}
+		}()
+	}
+}
+
+// procesarSiguiente toma el trabajo pendiente más antiguo (o uno abandonado por un
+// worker que se cayó) y lo ejecuta mientras renueva su "inicio", que hace de
+// concesión. Devuelve false si no había trabajos.
+func procesarSiguiente() bool {
+	ctx := context.TODO()
+	ahora := time.Now().Truncate(time.Millisecond)
+	filtro := bson.M{"$or": []bson.M{
+		{"estado": "pendiente", "fecha": bson.M{"$lte": ahora}},
+		{"estado": "procesando", "inicio": bson.M{"$lt": ahora.Add(-trabajoAbandonado)}},
+	}}
+	update := bson.M{
+		"$set": bson.M{"estado": "procesando", "inicio": ahora},
+		"$inc": bson.M{"intentos": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "fecha", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var trabajo models.TrabajoMedia
+	err := config.GetCollection("trabajos_media").FindOneAndUpdate(ctx, filtro, update, opts).Decode(&trabajo)
+	if err == mongo.ErrNoDocuments {
+		return false
+	}
+	if err != nil {
+		log.Println("Error tomando un trabajo de media:", err)
+		return false
+	}
+
+	detener := mantenerTrabajo(trabajo)
+	errTrabajo := ejecutarTrabajo(trabajo)
+	inicio := detener()
+
+	set := bson.M{"estado": "listo"}
+	if errTrabajo != nil {
+		log.Println("Error procesando el adjunto", trabajo.AdjuntoID.Hex(), "(intento", trabajo.Intentos, "):", errTrabajo)
+		// Se reintenta más tarde; fecha es desde cuándo puede volver a tomarse
+		set = bson.M{"estado": "pendiente", "error": errTrabajo.Error(), "fecha": time.Now().Add(esperaReintento * time.Duration(trabajo.Intentos))}
+		if trabajo.Intentos >= maxIntentosTrabajo {
+			set["estado"] = "fallido"
+		}
+	}
+	// Solo si el trabajo sigue siendo de este worker: si otro lo tomó, el estado es suyo
+	res, err := config.GetCollection("trabajos_media").UpdateOne(ctx,
+		bson.M{"_id": trabajo.ID, "estado": "procesando", "inicio": inicio},
+		bson.M{"$set": set},
+	)
+	if err != nil {
+		log.Println("Error actualizando el trabajo", trabajo.ID.Hex(), ":", err)
+		return true
+	}
+	if res.MatchedCount == 0 {
+		log.Println("El trabajo", trabajo.ID.Hex(), "lo tomó otro worker; se descarta este resultado")
+		return true
+	}
+	if set["estado"] == "fallido" {
+		if err := ActualizarAdjunto(trabajo.AdjuntoID, bson.M{"procesamiento": models.ProcesamientoFallido}); err != nil {
+			log.Println("Error marcando como fallido el adjunto", trabajo.AdjuntoID.Hex(), ":", err)
+		}
+	}
+	return true
+}
+
+// mantenerTrabajo renueva "inicio" cada renovarTrabajo mientras el trabajo se
+// ejecuta, para que un video largo (varios comandos de ffmpeg) no parezca
+// abandonado. Solo renueva si el trabajo sigue siendo de este worker. La función
+// devuelta detiene la renovación y devuelve el último inicio guardado.
+func mantenerTrabajo(trabajo models.TrabajoMedia) func() time.Time {
+	fin := make(chan struct{})
+	ultimo := make(chan time.Time)
+	go func() {
+		inicio := *trabajo.Inicio
+		ticker := time.NewTicker(renovarTrabajo)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-fin:
+				ultimo <- inicio
+				return
+			case <-ticker.C:
+				nuevo := time.Now().Truncate(time.Millisecond) // lo que guarda Mongo
+				res, err := config.GetCollection("trabajos_media").UpdateOne(context.TODO(),
+					bson.M{"_id": trabajo.ID, "estado": "procesando", "inicio": inicio},
+					bson.M{"$set": bson.M{"inicio": nuevo}},
+				)
+				if err != nil {
+					log.Println("Error renovando el trabajo", trabajo.ID.Hex(), ":", err)
+				} else if res.MatchedCount == 1 {
+					inicio = nuevo
+				}
+			}
+		}
+	}()
+	return func() time.Time {
+		close(fin)
+		return <-ultimo
+	}
+}
+
+// ejecutarTrabajo busca el adjunto del trabajo y le aplica su procesador. Un panic
+// en el procesador (p. ej. una imagen malformada) cuenta como un intento fallido.
+func ejecutarTrabajo(trabajo models.TrabajoMedia) (err error) {
+	procesar, ok := procesadores[trabajo.Tipo]
+	if !ok {
+		return fmt.Errorf("tipo de trabajo desconocido %q", trabajo.Tipo)
+	}
+	var adjunto models.Adjunto
+	err = config.GetCollection("adjuntos").FindOne(context.TODO(), bson.M{"_id": trabajo.AdjuntoID}).Decode(&adjunto)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return procesar(&adjunto)
+}
+
+// ActualizarAdjunto aplica set al adjunto y a las copias embebidas en los posts
+// que lo usan, y recalcula el estado de procesamiento de esos posts.
+func ActualizarAdjunto(adjuntoID primitive.ObjectID, set bson.M) error {
+	ctx := context.TODO()
+	if _, err := config.GetCollection("adjuntos").UpdateOne(ctx, bson.M{"_id": adjuntoID}, bson.M{"$set": set}); err != nil {
+		return err
+	}
+	embebido := bson.M{}
+	for campo, valor := range set {
+		embebido["adjuntos.$[a]."+campo] = valor
+	}
+	_, err := config.GetCollection("posts").UpdateMany(ctx,
+		bson.M{"adjuntos._id": adjuntoID},
+		bson.M{"$set": embebido},
+		options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"a._id": adjuntoID}}}),
+	)
+	if err != nil {
+		return err
+	}
+	return recalcularProcesamiento(bson.M{"adjuntos._id": adjuntoID})
+}
+
+// EstadoProcesamiento resume el estado de los adjuntos de un post: failed si alguno
+// falló, processing si alguno sigue en proceso, ready si todos terminaron y "" si
+// ninguno necesitaba procesamiento.
+func EstadoProcesamiento(adjuntos []models.Adjunto) string {
+	estado := ""
+	for _, a := range adjuntos {
+		switch a.Procesamiento {
+		case models.ProcesamientoFallido:
+			return models.ProcesamientoFallido
+		case models.ProcesamientoEnCurso:
+			estado = models.ProcesamientoEnCurso
+		case models.ProcesamientoListo:
+			if estado == "" {
+				estado = models.ProcesamientoListo
+			}
+		}
+	}
+	return estado
+}
+
+// recalcularProcesamiento aplica EstadoProcesamiento, dentro de Mongo, a los posts
+// que cumplen filtro.
+func recalcularProcesamiento(filtro bson.M) error {
+	estados := "$adjuntos.procesamiento"
+	_, err := config.GetCollection("posts").UpdateMany(context.TODO(), filtro, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"procesamiento": bson.M{"$switch": bson.M{
+			"branches": bson.A{
+				bson.M{"case": bson.M{"$in": bson.A{models.ProcesamientoFallido, estados}}, "then": models.ProcesamientoFallido},
+				bson.M{"case": bson.M{"$in": bson.A{models.ProcesamientoEnCurso, estados}}, "then": models.ProcesamientoEnCurso},
+				bson.M{"case": bson.M{"$in": bson.A{models.ProcesamientoListo, estados}}, "then": models.ProcesamientoListo},
+			},
+			"default": "$$REMOVE",
+		}}}}},
+	})
+	return err
+}
+
+// SincronizarAdjuntos copia a un post recién creado el estado actual de sus
+// adjuntos que seguían en proceso, por si el worker terminó entre que se leyeron y
+// se guardó el post (en ese caso ActualizarAdjunto no lo encontró).
+func SincronizarAdjuntos(post models.Post) {
+	ctx := context.TODO()
+	for _, embebido := range post.Adjuntos {
+		if embebido.Procesamiento != models.ProcesamientoEnCurso {
+			continue
+		}
+		var actual models.Adjunto
+		if err := config.GetCollection("adjuntos").FindOne(ctx, bson.M{"_id": embebido.ID}).Decode(&actual); err != nil {
+			continue
+		}
+		if actual.Procesamiento == models.ProcesamientoEnCurso {
+			continue
+		}
+		_, err := config.GetCollection("posts").UpdateOne(ctx,
+			bson.M{"_id": post.ID, "adjuntos._id": actual.ID},
+			bson.M{"$set": bson.M{"adjuntos.$": actual}},
+		)
+		if err != nil {
+			log.Println("Error sincronizando el adjunto", actual.ID.Hex(), "del post", post.ID.Hex(), ":", err)
+		}
+	}
+	if err := recalcularProcesamiento(bson.M{"_id": post.ID}); err != nil {
+		log.Println("Error recalculando el procesamiento del post", post.ID.Hex(), ":", err)
+	}
+}
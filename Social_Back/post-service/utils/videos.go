@@ -0,0 +1,316 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"post-service/models"
+	"post-service/storage"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	altoVideoWeb   = 720              // alto máximo del MP4 para navegadores
+	limiteFFmpeg   = 20 * time.Minute // por comando; el trabajo se renueva mientras corre
+	duracionSegHLS = 6                // segundos por segmento HLS
+)
+
+// escalonHLS es una calidad de la escalera HLS.
+type escalonHLS struct {
+	alto    int
+	maxrate int // kbit/s de video
+}
+
+// escaleraHLS son las calidades HLS; solo se generan las que no superan el alto
+// original (siempre al menos la primera).
+var escaleraHLS = []escalonHLS{{360, 800}, {720, 2800}, {1080, 5000}}
+
+// mimeSalidasVideo es el tipo de cada archivo generado según su extensión.
+var mimeSalidasVideo = map[string]string{
+	".jpg":  "image/jpeg",
+	".mp4":  "video/mp4",
+	".m3u8": "application/vnd.apple.mpegurl",
+	".ts":   "video/mp2t",
+}
+
+// ErrFFmpegNoDisponible indica que ffmpeg/ffprobe no están instalados.
+var ErrFFmpegNoDisponible = errors.New("ffmpeg no disponible")
+
+// binariosFFmpeg devuelve las rutas de ffmpeg y ffprobe (configurables con
+// FFMPEG_PATH y FFPROBE_PATH).
+func binariosFFmpeg() (string, string, error) {
+	ffmpeg, ffprobe := binario("FFMPEG_PATH", "ffmpeg"), binario("FFPROBE_PATH", "ffprobe")
+	if ffmpeg == "" || ffprobe == "" {
+		return "", "", ErrFFmpegNoDisponible
+	}
+	return ffmpeg, ffprobe, nil
+}
+
+// hlsHabilitado indica si se genera la escalera HLS (VIDEO_HLS=true). Es opcional
+// porque multiplica el tiempo de procesamiento y el espacio usado.
+func hlsHabilitado() bool {
+	activo, _ := strconv.ParseBool(os.Getenv("VIDEO_HLS"))
+	return activo
+}
+
+// sondeoVideo es lo que interesa de la salida de ffprobe.
+type sondeoVideo struct {
+	duracion    float64
+	ancho, alto int // ya con la rotación aplicada
+	codec       string
+	tieneAudio  bool
+}
+
+// ProcesarVideo sondea un video subido y genera su póster, un MP4 H.264/AAC con
+// faststart y, si VIDEO_HLS=true, una escalera HLS. Todo sale sin metadatos.
+func ProcesarVideo(adjunto *models.Adjunto) error {
+	ffmpeg, ffprobe, err := binariosFFmpeg()
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "video-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	original := filepath.Join(dir, "original"+filepath.Ext(adjunto.Archivo))
+	if err := descargarArchivo(ClaveArchivo(adjunto.Archivo), original); err != nil {
+		return err
+	}
+
+	sondeo, err := sondearVideo(ffprobe, original)
+	if err != nil {
+		return err
+	}
+	resultado := bson.M{
+		"ancho":    sondeo.ancho,
+		"alto":     sondeo.alto,
+		"duracion": sondeo.duracion,
+		"codec":    sondeo.codec,
+	}
+	salidas := map[string]string{} // archivo generado -> ruta local
+
+	// Póster: un fotograma cerca del inicio (no el primero, que suele ser negro)
+	poster := adjunto.Hash + "_poster.jpg"
+	segundo := math.Min(1, sondeo.duracion/2)
+	err = ejecutarFFmpeg(ffmpeg,
+		"-ss", strconv.FormatFloat(segundo, 'f', 2, 64), "-i", original,
+		"-frames:v", "1", "-vf", "scale='min(1280,iw)':-2", "-q:v", "3",
+		"-map_metadata", "-1", filepath.Join(dir, poster),
+	)
+	if err != nil {
+		return fmt.Errorf("generando el póster: %w", err)
+	}
+	salidas[poster] = filepath.Join(dir, poster)
+	resultado["poster"] = "/media/" + poster
+
+	// MP4 para reproducir directamente en el navegador
+	web := adjunto.Hash + "_web.mp4"
+	args := []string{"-i", original, "-vf", fmt.Sprintf("scale=-2:'trunc(min(%d,ih)/2)*2'", altoVideoWeb)}
+	args = append(args, argumentosH264(sondeo, 0)...)
+	args = append(args, "-movflags", "+faststart", filepath.Join(dir, web))
+	if err := ejecutarFFmpeg(ffmpeg, args...); err != nil {
+		return fmt.Errorf("generando el MP4: %w", err)
+	}
+	salidas[web] = filepath.Join(dir, web)
+	resultado["urlWeb"] = "/media/" + web
+
+	if hlsHabilitado() {
+		maestra, err := generarHLS(ffmpeg, adjunto.Hash, original, dir, sondeo, salidas)
+		if err != nil {
+			return fmt.Errorf("generando HLS: %w", err)
+		}
+		resultado["hls"] = "/media/" + maestra
+	}
+
+	derivados := make([]string, 0, len(salidas))
+	for archivo, ruta := range salidas {
+		if err := subirArchivoLocal(ruta, archivo); err != nil {
+			return err
+		}
+		derivados = append(derivados, archivo)
+	}
+	resultado["derivados"] = derivados
+
+	resultado["procesamiento"] = models.ProcesamientoListo
+	return ActualizarAdjunto(adjunto.ID, resultado)
+}
+
+// generarHLS codifica cada escalón de escaleraHLS y escribe la playlist maestra.
+// Las playlists usan rutas absolutas /media/ para que funcionen también cuando
+// ServirMedia redirige al almacenamiento. Devuelve el nombre de la maestra.
+func generarHLS(ffmpeg, hash, original, dir string, sondeo sondeoVideo, salidas map[string]string) (string, error) {
+	var maestra strings.Builder
+	maestra.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	for i, escalon := range escaleraHLS {
+		if i > 0 && escalon.alto > sondeo.alto {
+			break
+		}
+		prefijo := hash + "_hls" + strconv.Itoa(escalon.alto)
+		args := []string{"-i", original, "-vf", fmt.Sprintf("scale=-2:%d", escalon.alto)}
+		args = append(args, argumentosH264(sondeo, escalon.maxrate)...)
+		args = append(args,
+			"-f", "hls", "-hls_time", strconv.Itoa(duracionSegHLS), "-hls_playlist_type", "vod",
+			"-hls_base_url", "/media/",
+			"-hls_segment_filename", filepath.Join(dir, prefijo+"_%04d.ts"),
+			filepath.Join(dir, prefijo+".m3u8"),
+		)
+		if err := ejecutarFFmpeg(ffmpeg, args...); err != nil {
+			return "", err
+		}
+
+		generados, err := filepath.Glob(filepath.Join(dir, prefijo+"*"))
+		if err != nil {
+			return "", err
+		}
+		for _, ruta := range generados {
+			salidas[filepath.Base(ruta)] = ruta
+		}
+
+		ancho := escalon.alto * sondeo.ancho / max(1, sondeo.alto)
+		ancho += ancho % 2
+		fmt.Fprintf(&maestra, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d\n/media/%s.m3u8\n",
+			(escalon.maxrate+128)*1000, ancho, escalon.alto, prefijo)
+	}
+
+	nombre := hash + "_hls.m3u8"
+	ruta := filepath.Join(dir, nombre)
+	if err := os.WriteFile(ruta, []byte(maestra.String()), 0o644); err != nil {
+		return "", err
+	}
+	salidas[nombre] = ruta
+	return nombre, nil
+}
+
+// argumentosH264 son las opciones de codificación comunes: H.264 compatible con
+// cualquier navegador, AAC si hay audio y sin metadatos. maxrate 0 = sin tope.
+func argumentosH264(sondeo sondeoVideo, maxrate int) []string {
+	args := []string{
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "23",
+		"-pix_fmt", "yuv420p", "-profile:v", "high",
+		"-map_metadata", "-1", "-map_chapters", "-1",
+	}
+	if maxrate > 0 {
+		args = append(args, "-maxrate", strconv.Itoa(maxrate)+"k", "-bufsize", strconv.Itoa(2*maxrate)+"k")
+	}
+	if sondeo.tieneAudio {
+		return append(args, "-c:a", "aac", "-b:a", "128k")
+	}
+	return append(args, "-an")
+}
+
+// sondearVideo obtiene duración, resolución y códec con ffprobe.
+func sondearVideo(ffprobe, ruta string) (sondeoVideo, error) {
+	ctx, cancelar := context.WithTimeout(context.Background(), time.Minute)
+	defer cancelar()
+	out, err := exec.CommandContext(ctx, ffprobe,
+		"-v", "error", "-print_format", "json", "-show_format", "-show_streams", ruta,
+	).Output()
+	if err != nil {
+		return sondeoVideo{}, fmt.Errorf("ffprobe: %w", err)
+	}
+
+	var salida struct {
+		Streams []struct {
+			Tipo  string `json:"codec_type"`
+			Codec string `json:"codec_name"`
+			Ancho int    `json:"width"`
+			Alto  int    `json:"height"`
+			Tags  struct {
+				Rotar string `json:"rotate"`
+			} `json:"tags"`
+			DatosExtra []struct {
+				Rotacion float64 `json:"rotation"`
+			} `json:"side_data_list"`
+		} `json:"streams"`
+		Formato struct {
+			Duracion string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(out, &salida); err != nil {
+		return sondeoVideo{}, fmt.Errorf("leyendo ffprobe: %w", err)
+	}
+
+	var sondeo sondeoVideo
+	sondeo.duracion, _ = strconv.ParseFloat(salida.Formato.Duracion, 64)
+	encontrado := false
+	for _, s := range salida.Streams {
+		switch {
+		case s.Tipo == "audio":
+			sondeo.tieneAudio = true
+		case s.Tipo == "video" && !encontrado:
+			encontrado = true
+			sondeo.codec, sondeo.ancho, sondeo.alto = s.Codec, s.Ancho, s.Alto
+			rotacion, _ := strconv.ParseFloat(s.Tags.Rotar, 64)
+			for _, d := range s.DatosExtra {
+				if d.Rotacion != 0 {
+					rotacion = d.Rotacion
+				}
+			}
+			// ffmpeg aplica la rotación al transcodificar
+			if math.Mod(math.Abs(rotacion), 180) == 90 {
+				sondeo.ancho, sondeo.alto = sondeo.alto, sondeo.ancho
+			}
+		}
+	}
+	if !encontrado || sondeo.ancho == 0 || sondeo.alto == 0 {
+		return sondeoVideo{}, errors.New("el archivo no tiene una pista de video válida")
+	}
+	return sondeo, nil
+}
+
+// ejecutarFFmpeg corre ffmpeg sin interacción y devuelve su salida de error si falla.
+func ejecutarFFmpeg(ffmpeg string, args ...string) error {
+	ctx, cancelar := context.WithTimeout(context.Background(), limiteFFmpeg)
+	defer cancelar()
+	args = append([]string{"-nostdin", "-hide_banner", "-loglevel", "error", "-y"}, args...)
+	if out, err := exec.CommandContext(ctx, ffmpeg, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, out)
+	}
+	return nil
+}
+
+// descargarArchivo copia un objeto del almacenamiento a un archivo local, ya que
+// ffmpeg necesita poder recorrer la entrada.
+func descargarArchivo(clave, destino string) error {
+	obj, err := storage.Actual.Get(context.TODO(), clave)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+	f, err := os.Create(destino)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, obj); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// subirArchivoLocal guarda un archivo generado junto al original.
+func subirArchivoLocal(ruta, archivo string) error {
+	f, err := os.Open(ruta)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return storage.Actual.Put(context.TODO(), ClaveArchivo(archivo), f, info.Size(), mimeSalidasVideo[filepath.Ext(archivo)])
+}
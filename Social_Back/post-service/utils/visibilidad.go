@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"context"
+	"post-service/config"
+	"post-service/models"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EsVisibilidad indica si v es uno de los niveles de visibilidad de un post.
+func EsVisibilidad(v string) bool {
+	switch v {
+	case models.VisibilidadPublica, models.VisibilidadSeguidores, models.VisibilidadInstitucion,
+		models.VisibilidadGrupo, models.VisibilidadPrivada:
+		return true
+	}
+	return false
+}
+
+// FiltroVisibles es la política de visibilidad de los posts: selecciona los que
+// viewer puede ver. Un post es visible para su autor siempre (también sus
+// borradores y programados) y para el resto si ya se publicó, no hay un bloqueo,
+// la cuenta del autor no es privada (o viewer la sigue) y su visibilidad lo
+// incluye. Todas las lecturas la usan, los listados como filtro y
+// los posts sueltos a través de PuedeVerPost, para que nunca discrepen.
+func FiltroVisibles(viewer primitive.ObjectID) (bson.M, error) {
+	ocultos, err := AutoresPrivadosOcultos(viewer)
+	if err != nil {
+		return nil, err
+	}
+	bloqueados, err := UsuariosBloqueados(viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	audiencia := []bson.M{
+		{"visibilidad": bson.M{"$in": bson.A{nil, models.VisibilidadPublica}}},
+	}
+	var propios bson.M
+	if !viewer.IsZero() {
+		ctx := context.TODO()
+		valores, err := config.GetCollection("follows").Distinct(ctx, "seguidoId", bson.M{"seguidorId": viewer})
+		if err != nil {
+			return nil, err
+		}
+		seguidos := IDsDeDistinct(valores)
+		valores, err = config.GetCollection("grupos").Distinct(ctx, "_id", bson.M{"miembros": viewer})
+		if err != nil {
+			return nil, err
+		}
+		grupos := IDsDeDistinct(valores)
+		institucion, err := InstitucionDe(viewer)
+		if err != nil {
+			return nil, err
+		}
+
+		propios = bson.M{"autorId": viewer}
+		audiencia = append(audiencia,
+			bson.M{"visibilidad": models.VisibilidadSeguidores, "autorId": bson.M{"$in": seguidos}},
+			bson.M{"visibilidad": models.VisibilidadGrupo, "grupoId": bson.M{"$in": grupos}},
+		)
+		if institucion != "" {
+			audiencia = append(audiencia, bson.M{"visibilidad": models.VisibilidadInstitucion, "institucion": institucion})
+		}
+	}
+
+	filtro := bson.M{"$and": []bson.M{FiltroPublicados(), {"$or": audiencia}}}
+	if propios != nil {
+		filtro = bson.M{"$or": []bson.M{propios, filtro}}
+	}
+	if excluidos := append(ocultos, bloqueados...); len(excluidos) > 0 {
+		filtro["autorId"] = bson.M{"$nin": excluidos}
+	}
+	return filtro, nil
+}
+
+// FiltroListado es FiltroVisibles sin los autores que viewer silenció ni los
+// borradores y programados del propio viewer: lo que se muestra en los listados,
+// la búsqueda y los feeds.
+func FiltroListado(viewer primitive.ObjectID) (bson.M, error) {
+	filtro, err := FiltroVisibles(viewer)
+	if err != nil {
+		return nil, err
+	}
+	silenciados, err := UsuariosSilenciados(viewer)
+	if err != nil {
+		return nil, err
+	}
+	condiciones := []bson.M{filtro, FiltroPublicados()}
+	if len(silenciados) > 0 {
+		condiciones = append(condiciones, bson.M{"autorId": bson.M{"$nin": silenciados}})
+	}
+	return bson.M{"$and": condiciones}, nil
+}
+
+// PuedeVerPost indica si viewer puede ver el post, según FiltroVisibles.
+func PuedeVerPost(viewer, postID primitive.ObjectID) (bool, error) {
+	filtro, err := FiltroVisibles(viewer)
+	if err != nil {
+		return false, err
+	}
+	n, err := config.GetCollection("posts").CountDocuments(context.TODO(),
+		bson.M{"$and": []bson.M{{"_id": postID}, filtro}},
+		options.Count().SetLimit(1),
+	)
+	return n > 0, err
+}
+
+// InstitucionDe devuelve la institución del usuario según Institucion ("" si no
+// se puede saber).
+func InstitucionDe(usuarioID primitive.ObjectID) (string, error) {
+	var usuario struct {
+		Codigo string `bson:"codigo_institucional"`
+	}
+	err := config.GetCollection("users").FindOne(context.TODO(), bson.M{"_id": usuarioID},
+		options.FindOne().SetProjection(bson.M{"codigo_institucional": 1})).Decode(&usuario)
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return Institucion(usuario.Codigo), nil
+}
+
+// Institucion es la única regla para saber la institución de un código
+// institucional, la usan la visibilidad y las sugerencias: si tiene forma de
+// correo ("a0123@uni.edu") es su dominio. Un código de matrícula ("20210345") no
+// dice de qué institución es y devuelve "", igual que un código vacío.
+func Institucion(codigo string) string {
+	codigo = strings.ToLower(strings.TrimSpace(codigo))
+	i := strings.LastIndex(codigo, "@")
+	if i < 0 {
+		return ""
+	}
+	return codigo[i+1:]
+}
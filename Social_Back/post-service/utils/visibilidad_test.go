@@ -0,0 +1,27 @@
+package utils
+
+import "testing"
+
+func TestInstitucion(t *testing.T) {
+	casos := []struct {
+		codigo string
+		quiere string
+	}{
+		{"a0123@uni.edu", "uni.edu"},
+		{"  A0123@UNI.Edu  ", "uni.edu"},
+		{"a0123@alumnos.uni.edu", "alumnos.uni.edu"},
+		{"a@b@otra.edu", "otra.edu"}, // cuenta lo que va después del último @
+		{"@uni.edu", "uni.edu"},
+		{"20210345", ""}, // un código de matrícula no dice la institución
+		{"2021-0345", ""},
+		{"a0123@", ""}, // sin dominio no hay institución
+		{"@", ""},
+		{"", ""},
+		{"   ", ""},
+	}
+	for _, c := range casos {
+		if got := Institucion(c.codigo); got != c.quiere {
+			t.Errorf("Institucion(%q) = %q, quiere %q", c.codigo, got, c.quiere)
+		}
+	}
+}
@@ -359,6 +359,7 @@ export const postsService = {
           ...authStore.getAuthHeader(),
           'Content-Type': 'application/json',
         },
+        body: { usuarioId: authStore.user?.id },
       });
       
       if (error.value) {
@@ -40,11 +40,25 @@ export interface TextPost extends BasePost {
   textContent: string;
 }
 
+/**
+ * Archivo adjunto a un post, tal como lo devuelve el post-service
+ */
+export interface Adjunto {
+  id: string;
+  tipo: string;
+  url: string;
+  /** srcset de las variantes WebP/JPEG generadas al procesar una imagen */
+  srcsetWebp?: string;
+  srcsetJpeg?: string;
+  blurhash?: string;
+}
+
 /**
  * Post que contiene contenido multimedia (imagen o video)
  */
 export interface MediaPost extends BasePost {
   mediaUrl: string;
+  adjuntos?: Adjunto[];
   caption: string;
   location?: string;
 }